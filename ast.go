@@ -1,5 +1,7 @@
 package minimalisp
 
+import "sync"
+
 // Expression is the interface all types of expressions must fulfil.
 type Expression interface {
 	Accept(visitor visitor) (interface{}, error)
@@ -10,12 +12,24 @@ type visitor interface {
 	visitLiteralExpr(literalExpr *LiteralExpr) (interface{}, error)
 	visitDefvarExpr(defvarExpr *DefvarExpr) (interface{}, error)
 	visitVarExpr(varExpr *VarExpr) (interface{}, error)
+	visitLocalExpr(localExpr *LocalExpr) (interface{}, error)
 	visitIfExpr(ifExpr *IfExpr) (interface{}, error)
 	visitDefunExpr(defunExpr *DefunExpr) (interface{}, error)
 	visitFuncCallExpr(funcCallExpr *FuncCallExpr) (interface{}, error)
+	visitLocalCallExpr(localCallExpr *LocalCallExpr) (interface{}, error)
 	visitListExpr(listExpr *ListExpr) (interface{}, error)
 	visitLetExpr(letExpr *LetExpr) (interface{}, error)
+	visitLetStarExpr(letStarExpr *LetStarExpr) (interface{}, error)
+	visitLetrecExpr(letrecExpr *LetrecExpr) (interface{}, error)
 	visitLambdaExpr(lambdaExpr *LambdaExpr) (interface{}, error)
+	visitDestructureExpr(destructureExpr *DestructureExpr) (interface{}, error)
+	visitModuleExpr(moduleExpr *ModuleExpr) (interface{}, error)
+	visitRequireExpr(requireExpr *RequireExpr) (interface{}, error)
+	visitDefstructExpr(defstructExpr *DefstructExpr) (interface{}, error)
+	visitMatchExpr(matchExpr *MatchExpr) (interface{}, error)
+	visitSelectExpr(selectExpr *SelectExpr) (interface{}, error)
+	visitDefparameterExpr(defparameterExpr *DefparameterExpr) (interface{}, error)
+	visitParameterizeExpr(parameterizeExpr *ParameterizeExpr) (interface{}, error)
 }
 
 // LiteralExpr is a literal such as a string or a number.
@@ -49,6 +63,20 @@ func (e *VarExpr) Accept(visitor visitor) (interface{}, error) {
 	return visitor.visitVarExpr(e)
 }
 
+// LocalExpr is a reference to a local variable. The resolver generates it
+// from a VarExpr. The variable is found in the environment Depth levels up
+// the chain of enclosing environments at position Slot.
+type LocalExpr struct {
+	Name  Token
+	Depth int
+	Slot  int
+}
+
+// Accept visits the local expression.
+func (e *LocalExpr) Accept(visitor visitor) (interface{}, error) {
+	return visitor.visitLocalExpr(e)
+}
+
 // IfExpr is an if expression :).
 type IfExpr struct {
 	Condition  Expression
@@ -65,6 +93,7 @@ func (e *IfExpr) Accept(visitor visitor) (interface{}, error) {
 type DefunExpr struct {
 	Name   Token
 	Params []Token
+	Doc    string
 	Body   Expression
 }
 
@@ -73,6 +102,17 @@ func (e *DefunExpr) Accept(visitor visitor) (interface{}, error) {
 	return visitor.visitDefunExpr(e)
 }
 
+// DefstructExpr is a definition of a record type.
+type DefstructExpr struct {
+	Name   Token
+	Fields []Token
+}
+
+// Accept visits the struct definition.
+func (e *DefstructExpr) Accept(visitor visitor) (interface{}, error) {
+	return visitor.visitDefstructExpr(e)
+}
+
 // FuncCallExpr is a function call.
 type FuncCallExpr struct {
 	Name      Token
@@ -84,6 +124,20 @@ func (e *FuncCallExpr) Accept(visitor visitor) (interface{}, error) {
 	return visitor.visitFuncCallExpr(e)
 }
 
+// LocalCallExpr is a call of a function bound to a local variable. The
+// resolver generates it from a FuncCallExpr.
+type LocalCallExpr struct {
+	Name      Token
+	Depth     int
+	Slot      int
+	Arguments []Expression
+}
+
+// Accept visits the local function call.
+func (e *LocalCallExpr) Accept(visitor visitor) (interface{}, error) {
+	return visitor.visitLocalCallExpr(e)
+}
+
 // ListExpr represents a list collection.
 type ListExpr struct {
 	Elements []Expression
@@ -94,7 +148,8 @@ func (e *ListExpr) Accept(visitor visitor) (interface{}, error) {
 	return visitor.visitListExpr(e)
 }
 
-// LetExpr is a let expression to define local variables.
+// LetExpr is a let expression to define local variables. All values are
+// evaluated before any variable is defined, so they can not refer to each other.
 type LetExpr struct {
 	Names  []Token
 	Values []Expression
@@ -106,6 +161,32 @@ func (e *LetExpr) Accept(visitor visitor) (interface{}, error) {
 	return visitor.visitLetExpr(e)
 }
 
+// LetStarExpr defines local variables one after another. Each value can
+// refer to the variables defined before it.
+type LetStarExpr struct {
+	Names  []Token
+	Values []Expression
+	Body   Expression
+}
+
+// Accept visits the let* expression.
+func (e *LetStarExpr) Accept(visitor visitor) (interface{}, error) {
+	return visitor.visitLetStarExpr(e)
+}
+
+// LetrecExpr defines local variables which can all refer to each other,
+// which allows defining mutually recursive local functions.
+type LetrecExpr struct {
+	Names  []Token
+	Values []Expression
+	Body   Expression
+}
+
+// Accept visits the letrec expression.
+func (e *LetrecExpr) Accept(visitor visitor) (interface{}, error) {
+	return visitor.visitLetrecExpr(e)
+}
+
 // LambdaExpr is a lambda expression to define anonymous functions.
 type LambdaExpr struct {
 	Params []Token
@@ -116,3 +197,110 @@ type LambdaExpr struct {
 func (e *LambdaExpr) Accept(visitor visitor) (interface{}, error) {
 	return visitor.visitLambdaExpr(e)
 }
+
+// DestructureExpr binds the parts of a value to the variables of a pattern
+// and evaluates its body with them. The parser generates it for let
+// bindings and parameters which use destructuring patterns.
+type DestructureExpr struct {
+	Pattern Pattern
+	Value   Expression
+	Body    Expression
+}
+
+// Accept visits the destructure expression.
+func (e *DestructureExpr) Accept(visitor visitor) (interface{}, error) {
+	return visitor.visitDestructureExpr(e)
+}
+
+// ModuleExpr declares the name and the exported variables of a module.
+type ModuleExpr struct {
+	Name    Token
+	Exports []Token
+}
+
+// Accept visits the module expression.
+func (e *ModuleExpr) Accept(visitor visitor) (interface{}, error) {
+	return visitor.visitModuleExpr(e)
+}
+
+// RequireExpr loads a module and binds it to an alias. Without an alias
+// the name of the module is used.
+type RequireExpr struct {
+	Keyword Token
+	Path    Token
+	Alias   Token
+}
+
+// Accept visits the require expression.
+func (e *RequireExpr) Accept(visitor visitor) (interface{}, error) {
+	return visitor.visitRequireExpr(e)
+}
+
+// MatchExpr evaluates the body of the first clause whose pattern matches
+// the value.
+type MatchExpr struct {
+	Keyword Token
+	Value   Expression
+	Clauses []MatchClause
+
+	compile  sync.Once
+	matchers []matcher
+}
+
+// MatchClause is a pattern and the body which is evaluated if it matches.
+type MatchClause struct {
+	Pattern Pattern
+	Body    Expression
+}
+
+// Accept visits the match expression.
+func (e *MatchExpr) Accept(visitor visitor) (interface{}, error) {
+	return visitor.visitMatchExpr(e)
+}
+
+// SelectExpr waits until one of several channel operations can proceed and
+// evaluates the body of its clause.
+type SelectExpr struct {
+	Keyword Token
+	Clauses []SelectClause
+}
+
+// SelectClause is a clause of a select expression. Kind is either recv,
+// send or default. Recv clauses bind the received value to Name and send
+// clauses send Value.
+type SelectClause struct {
+	Kind    Token
+	Channel Expression
+	Name    Token
+	Value   Expression
+	Body    Expression
+}
+
+// Accept visits the select expression.
+func (e *SelectExpr) Accept(visitor visitor) (interface{}, error) {
+	return visitor.visitSelectExpr(e)
+}
+
+// DefparameterExpr is a definition of a dynamic variable.
+type DefparameterExpr struct {
+	Name        Token
+	Initializer Expression
+}
+
+// Accept visits the defparameter expression.
+func (e *DefparameterExpr) Accept(visitor visitor) (interface{}, error) {
+	return visitor.visitDefparameterExpr(e)
+}
+
+// ParameterizeExpr rebinds dynamic variables while its body is evaluated.
+type ParameterizeExpr struct {
+	Keyword Token
+	Names   []Token
+	Values  []Expression
+	Body    Expression
+}
+
+// Accept visits the parameterize expression.
+func (e *ParameterizeExpr) Accept(visitor visitor) (interface{}, error) {
+	return visitor.visitParameterizeExpr(e)
+}
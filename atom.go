@@ -0,0 +1,319 @@
+package minimalisp
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// Atom holds a value which can be changed safely by multiple goroutines.
+// Changes are made with compare-and-swap, so update functions may be called
+// more than once and must not have side effects.
+type Atom struct {
+	// state points to the current atomState.
+	state unsafe.Pointer
+
+	mutex   sync.Mutex
+	watches []watchEntry
+}
+
+// atomState boxes the value of an atom so that its pointer can be swapped.
+type atomState struct {
+	value interface{}
+}
+
+// Watch is called after the value of an atom changed.
+type Watch func(key interface{}, atom *Atom, old, new interface{}) error
+
+type watchEntry struct {
+	key   interface{}
+	watch Watch
+}
+
+// NewAtom is a factory function to create a new atom.
+func NewAtom(value interface{}) *Atom {
+	return &Atom{state: unsafe.Pointer(&atomState{value})}
+}
+
+// Deref returns the current value of the atom.
+func (a *Atom) Deref() interface{} {
+	return (*atomState)(atomic.LoadPointer(&a.state)).value
+}
+
+// Reset sets the value of the atom and notifies the watches.
+func (a *Atom) Reset(value interface{}) error {
+	old := (*atomState)(atomic.SwapPointer(&a.state, unsafe.Pointer(&atomState{value})))
+	return a.notify(old.value, value)
+}
+
+// Swap sets the value of the atom to the result of applying update to the
+// current value. If another goroutine changed the value in the meantime,
+// update is applied again to the new value.
+func (a *Atom) Swap(update func(old interface{}) (interface{}, error)) (interface{}, error) {
+	for {
+		old := atomic.LoadPointer(&a.state)
+		oldValue := (*atomState)(old).value
+
+		value, err := update(oldValue)
+		if err != nil {
+			return nil, err
+		}
+
+		if atomic.CompareAndSwapPointer(&a.state, old, unsafe.Pointer(&atomState{value})) {
+			return value, a.notify(oldValue, value)
+		}
+	}
+}
+
+// AddWatch registers a watch which is called after every change of the
+// atom. A watch with the same key is replaced.
+func (a *Atom) AddWatch(key interface{}, watch Watch) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for n, entry := range a.watches {
+		if entry.key == key {
+			a.watches[n].watch = watch
+			return
+		}
+	}
+
+	a.watches = append(a.watches, watchEntry{key, watch})
+}
+
+// RemoveWatch removes the watch with the given key.
+func (a *Atom) RemoveWatch(key interface{}) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for n, entry := range a.watches {
+		if entry.key == key {
+			a.watches = append(a.watches[:n:n], a.watches[n+1:]...)
+			return
+		}
+	}
+}
+
+// notify calls the watches in the order they were added.
+func (a *Atom) notify(old, new interface{}) error {
+	a.mutex.Lock()
+	watches := a.watches
+	a.mutex.Unlock()
+
+	for _, entry := range watches {
+		if err := entry.watch(entry.key, a, old, new); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *Atom) String() string {
+	return fmt.Sprintf("<atom %v>", a.Deref())
+}
+
+// AtomBuiltin creates an atom.
+// Usage:
+// (atom 0)
+type AtomBuiltin struct{}
+
+// Arity returns 1.
+func (f *AtomBuiltin) Arity() int {
+	return 1
+}
+
+// Call implements atom.
+func (f *AtomBuiltin) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	return NewAtom(arguments[0]), nil
+}
+
+func (f *AtomBuiltin) String() string {
+	return "<atom>"
+}
+
+// Deref returns the value of an atom, a future or a promise. Futures and
+// promises block until their value is available or an optional timeout in
+// milliseconds elapsed, in which case the timeout value is returned.
+// Usage:
+// (deref (atom 1)) => 1
+// (deref (promise) 10 "timeout") => "timeout"
+type Deref struct{}
+
+// Arity returns infiniteArity as the timeout is optional.
+func (f *Deref) Arity() int {
+	return infiniteArity
+}
+
+// Call implements deref.
+func (f *Deref) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	if len(arguments) != 1 && len(arguments) != 3 {
+		return nil, &executionError{line, "<deref> expects a reference and an optional timeout and timeout value"}
+	}
+
+	switch ref := arguments[0].(type) {
+	case *Atom:
+		return ref.Deref(), nil
+	case pending:
+		if len(arguments) == 1 {
+			<-ref.done()
+			return ref.result()
+		}
+
+		timeout, ok := arguments[1].(float64)
+		if !ok || timeout < 0 {
+			return nil, &executionError{line, "<deref> expects a non-negative timeout in milliseconds"}
+		}
+
+		return waitFor(ref, time.Duration(timeout*float64(time.Millisecond)), arguments[2])
+	}
+
+	return nil, &executionError{line, "<deref> expects an atom, a future or a promise as first parameter"}
+}
+
+func (f *Deref) String() string {
+	return "<deref>"
+}
+
+// Reset sets the value of an atom and returns the new value.
+// Usage:
+// (reset! a 2) => 2
+type Reset struct{}
+
+// Arity returns 2.
+func (f *Reset) Arity() int {
+	return 2
+}
+
+// Call implements reset!.
+func (f *Reset) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	a, err := expectAtom(line, "reset!", arguments[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.Reset(arguments[1]); err != nil {
+		return nil, err
+	}
+
+	return arguments[1], nil
+}
+
+func (f *Reset) String() string {
+	return "<reset!>"
+}
+
+// Swap applies a function to the value of an atom and additional arguments
+// and stores the result. The function may be called more than once if other
+// tasks change the atom concurrently.
+// Usage:
+// (swap! a + 1)
+type Swap struct{}
+
+// Arity returns infiniteArity as additional arguments are passed to the function.
+func (f *Swap) Arity() int {
+	return infiniteArity
+}
+
+// Call implements swap!.
+func (f *Swap) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	if len(arguments) < 2 {
+		return nil, &executionError{line, "<swap!> expects an atom, a function and its additional arguments"}
+	}
+
+	a, err := expectAtom(line, "swap!", arguments[0])
+	if err != nil {
+		return nil, err
+	}
+
+	fun, ok := arguments[1].(Function)
+	if !ok {
+		return nil, &executionError{line, "<swap!> expects a function as second parameter"}
+	}
+
+	return a.Swap(func(old interface{}) (interface{}, error) {
+		args := append([]interface{}{old}, arguments[2:]...)
+		return callFunction(line, i, fun, args)
+	})
+}
+
+func (f *Swap) String() string {
+	return "<swap!>"
+}
+
+// AddWatchBuiltin registers a function which is called with the key, the
+// atom, the old and the new value after every change of an atom.
+// Usage:
+// (add-watch a "log" (lambda (key a old new) (println old "->" new)))
+type AddWatchBuiltin struct{}
+
+// Arity returns 3.
+func (f *AddWatchBuiltin) Arity() int {
+	return 3
+}
+
+// Call implements add-watch.
+func (f *AddWatchBuiltin) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	a, err := expectAtom(line, "add-watch", arguments[0])
+	if err != nil {
+		return nil, err
+	}
+
+	fun, ok := arguments[2].(Function)
+	if !ok || (fun.Arity() != 4 && fun.Arity() != infiniteArity) {
+		return nil, &executionError{line, "<add-watch> expects a function which accepts 4 arguments as third parameter"}
+	}
+
+	// Watches run on the goroutine which changed the atom, so every call
+	// gets a task of its own.
+	task := i.fork()
+
+	a.AddWatch(arguments[1], func(key interface{}, atom *Atom, old, new interface{}) error {
+		_, err := fun.Call(line, task.fork(), []interface{}{key, atom, old, new})
+		return err
+	})
+
+	return a, nil
+}
+
+func (f *AddWatchBuiltin) String() string {
+	return "<add-watch>"
+}
+
+// RemoveWatchBuiltin removes a watch from an atom.
+// Usage:
+// (remove-watch a "log")
+type RemoveWatchBuiltin struct{}
+
+// Arity returns 2.
+func (f *RemoveWatchBuiltin) Arity() int {
+	return 2
+}
+
+// Call implements remove-watch.
+func (f *RemoveWatchBuiltin) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	a, err := expectAtom(line, "remove-watch", arguments[0])
+	if err != nil {
+		return nil, err
+	}
+
+	a.RemoveWatch(arguments[1])
+
+	return a, nil
+}
+
+func (f *RemoveWatchBuiltin) String() string {
+	return "<remove-watch>"
+}
+
+// expectAtom makes sure that a builtin received an atom.
+func expectAtom(line int, name string, arg interface{}) (*Atom, error) {
+	a, ok := arg.(*Atom)
+	if !ok {
+		return nil, &executionError{line, fmt.Sprintf("<%s> expects an atom as first parameter", name)}
+	}
+
+	return a, nil
+}
@@ -0,0 +1,107 @@
+package minimalisp_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	. "bakku.dev/minimalisp"
+)
+
+func TestAtom_Builtins(t *testing.T) {
+	expectResult(t, "(deref (atom 1))", "1")
+	expectResult(t, "(defvar a (atom 1)) (reset! a 5) (deref a)", "5")
+	expectResult(t, "(defvar a (atom 1)) (swap! a + 2 3)", "6")
+	expectResult(t, "(defvar a (atom '())) (swap! a (lambda (l) (cons 1 l))) (swap! a (lambda (l) (cons 2 l)))", "(2 1)")
+	expectResult(t, "(atom 3)", "<atom 3>")
+
+	expectError(t, "(deref 1)")
+	expectError(t, "(swap! (atom 1) 2)")
+	expectError(t, "(swap! (atom 1))")
+}
+
+func TestAtom_SwapFromManyTasks(t *testing.T) {
+	src := `
+	(defvar counter (atom 0))
+	(defvar tasks (map (lambda (n) (spawn (lambda () (swap! counter + 1)))) (range 100)))
+	(map recv tasks)
+	(deref counter)`
+
+	expectResult(t, src, "100")
+}
+
+func TestAtom_Watches(t *testing.T) {
+	src := `
+	(defvar log (atom '()))
+	(defvar a (atom 1))
+	(add-watch a "log" (lambda (key a old new) (swap! log (lambda (l) (concat l '(key old new))))))
+	(reset! a 2)
+	(swap! a * 10)
+	(remove-watch a "log")
+	(reset! a 3)
+	(deref log)`
+
+	expectResult(t, src, "(log 1 2 log 2 20)")
+
+	err := expectError(t, "(defvar a (atom 1)) (add-watch a 1 (lambda (k a o n) (/ 1 0))) (reset! a 2)")
+	if !strings.Contains(err.Error(), "Division by zero") {
+		t.Fatalf("Expected division by zero error, got %v", err)
+	}
+
+	expectError(t, "(add-watch (atom 1) 1 (lambda (x) x))")
+}
+
+func TestAtom_SharedWithGo(t *testing.T) {
+	counter := NewAtom(0.0)
+
+	var notified int
+	var mutex sync.Mutex
+
+	counter.AddWatch("count", func(key interface{}, atom *Atom, old, new interface{}) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		notified++
+		return nil
+	})
+
+	interpreter := NewInterpreter()
+	if err := interpreter.Define("counter", counter); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expressions := parse(t, "(map recv (map (lambda (n) (spawn swap! counter + 1)) (range 50)))")
+
+	var wg sync.WaitGroup
+
+	for n := 0; n < 4; n++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			if _, err := interpreter.Interpret(expressions); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			for k := 0; k < 50; k++ {
+				_, _ = counter.Swap(func(old interface{}) (interface{}, error) {
+					return old.(float64) + 1, nil
+				})
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if counter.Deref() != 400.0 {
+		t.Fatalf("Expected 400, got %v", counter.Deref())
+	}
+
+	if notified != 400 {
+		t.Fatalf("Expected 400 notifications, got %d", notified)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"bakku.dev/minimalisp"
+	"github.com/peterh/liner"
+)
+
+// debugSession is the user interface of the debugger.
+type debugSession struct {
+	line     *liner.State
+	debugger *minimalisp.Debugger
+	// source holds the lines of the debugged script.
+	source []string
+	// last is the previous command, which an empty input repeats.
+	last string
+}
+
+const debugHelp = `  break line|name (b)  pause at a line or whenever a function is called
+  clear                remove all breakpoints
+  continue (c)         run until the next breakpoint
+  step (s)             pause at the next call or definition
+  next (n)             step over the current expression
+  out (o)              step out of the current function
+  stack (bt)           show the call stack
+  print expr (p)       evaluate an expression in the paused environment
+  env                  list the local variables
+  list (l)             show the source around the current line
+  quit (q)             stop debugging
+An empty line repeats the previous command.`
+
+// runDebug runs a script in the debugger and returns the exit code.
+func runDebug(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: mlisp debug script [args ...]")
+		return exitUsage
+	}
+
+	filename := args[0]
+
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not read file: %v\n", err)
+		return exitNoInput
+	}
+
+	line := liner.NewLiner()
+	defer line.Close()
+
+	line.SetCtrlCAborts(true)
+
+	s := &debugSession{line: line, source: strings.Split(string(src), "\n")}
+	s.debugger = minimalisp.NewDebugger(s.pause)
+
+	interpreter := minimalisp.NewInterpreter(minimalisp.WithDebugger(s.debugger))
+	defineArgs(interpreter, args[1:])
+
+	fmt.Printf("Debugging %s, type help for the commands.\n", filename)
+
+	ret, err := interpretSource(interpreter, filename, string(src))
+	if err == nil {
+		err = minimalisp.Realize(ret)
+	}
+
+	if err != nil {
+		return exitCode(err)
+	}
+
+	fmt.Println(fmt.Sprintf("=> %v", ret))
+	return 0
+}
+
+// pause shows where the script paused and reads commands until the script
+// continues.
+func (s *debugSession) pause(p *minimalisp.Pause) minimalisp.Step {
+	where := "top level"
+	if stack := p.Stack(); len(stack) > 0 {
+		where = stack[0].Name
+	}
+
+	fmt.Printf("paused at line %d in %s\n", p.Line, where)
+	s.list(p.Line, 0)
+
+	for {
+		input, err := s.line.Prompt("(debug) ")
+		if err == liner.ErrPromptAborted {
+			continue
+		}
+
+		// Without input the script runs to its end.
+		if err != nil {
+			s.debugger.ClearBreakpoints()
+			return minimalisp.Continue
+		}
+
+		input = strings.TrimSpace(input)
+		if input == "" {
+			input = s.last
+		} else {
+			s.line.AppendHistory(input)
+		}
+
+		s.last = input
+
+		command, arg := input, ""
+		if n := strings.IndexAny(input, " \t"); n >= 0 {
+			command, arg = input[:n], strings.TrimSpace(input[n:])
+		}
+
+		switch command {
+		case "":
+		case "c", "continue":
+			return minimalisp.Continue
+		case "s", "step":
+			return minimalisp.StepIn
+		case "n", "next":
+			return minimalisp.StepOver
+		case "o", "out":
+			return minimalisp.StepOut
+		case "b", "break":
+			s.breakpoint(arg)
+		case "clear":
+			s.debugger.ClearBreakpoints()
+			fmt.Println("removed all breakpoints")
+		case "bt", "stack":
+			for n, frame := range p.Stack() {
+				fmt.Printf("#%d %s called on line %d\n", n, frame.Name, frame.Line)
+			}
+
+			fmt.Printf("#%d top level\n", len(p.Stack()))
+		case "p", "print":
+			ret, err := p.Eval(arg)
+			if err == nil {
+				err = minimalisp.Realize(ret)
+			}
+
+			if err != nil {
+				fmt.Println(err)
+			} else {
+				fmt.Println(fmt.Sprintf("=> %v", ret))
+			}
+		case "env":
+			env := p.Environment()
+
+			for _, name := range env.Names() {
+				val, _ := env.Get(minimalisp.Token{TokenType: minimalisp.Identifier, Lexeme: name, Line: -1})
+				fmt.Printf("%s = %v\n", name, val)
+			}
+		case "l", "list":
+			s.list(p.Line, 3)
+		case "q", "quit":
+			s.line.Close()
+			os.Exit(0)
+		case "h", "help":
+			fmt.Println(debugHelp)
+		default:
+			fmt.Printf("unknown command %s, see help\n", command)
+		}
+	}
+}
+
+func (s *debugSession) breakpoint(arg string) {
+	if arg == "" {
+		fmt.Println("Usage: break line|name")
+		return
+	}
+
+	if line, err := strconv.Atoi(arg); err == nil {
+		s.debugger.BreakAtLine(line)
+		fmt.Printf("breakpoint at line %d\n", line)
+		return
+	}
+
+	s.debugger.BreakAtFunction(arg)
+	fmt.Printf("breakpoint at function %s\n", arg)
+}
+
+// list prints the source lines around a line and marks the line itself.
+func (s *debugSession) list(line, context int) {
+	for n := line - context; n <= line+context; n++ {
+		if n < 1 || n > len(s.source) {
+			continue
+		}
+
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+
+		fmt.Printf("%s %4d  %s\n", marker, n, s.source[n-1])
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"bakku.dev/minimalisp"
+)
+
+// runFmt formats source files in place. Without files it formats the
+// standard input. It returns the exit code of the command.
+func runFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	check := flags.Bool("check", false, "list files which are not formatted instead of rewriting them")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: mlisp fmt [--check] [file ...]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not read stdin: %v\n", err)
+			return 1
+		}
+
+		formatted, err := minimalisp.Format(string(src))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "<stdin>: %v\n", err)
+			return 1
+		}
+
+		if *check {
+			if formatted != string(src) {
+				fmt.Println("<stdin>")
+				return 1
+			}
+
+			return 0
+		}
+
+		fmt.Print(formatted)
+		return 0
+	}
+
+	code := 0
+
+	for _, filename := range flags.Args() {
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not read file: %v\n", err)
+			code = 1
+			continue
+		}
+
+		formatted, err := minimalisp.Format(string(src))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", filename, err)
+			code = 1
+			continue
+		}
+
+		if formatted == string(src) {
+			continue
+		}
+
+		if *check {
+			fmt.Println(filename)
+			code = 1
+			continue
+		}
+
+		if err := ioutil.WriteFile(filename, []byte(formatted), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "could not write file: %v\n", err)
+			code = 1
+		}
+	}
+
+	return code
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/peterh/liner"
+)
+
+// historySize is the maximum amount of entries kept in the history file.
+const historySize = 500
+
+// historyFile returns the path of the history file in the home directory.
+func historyFile() (string, bool) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", false
+	}
+
+	return filepath.Join(home, ".mlisp_history"), true
+}
+
+// loadHistory reads the history of previous sessions. A missing history
+// file is not an error.
+func loadHistory(line *liner.State) {
+	path, ok := historyFile()
+	if !ok {
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	_, _ = line.ReadHistory(f)
+}
+
+// saveHistory writes the last historySize entries of the history.
+func saveHistory(line *liner.State) error {
+	path, ok := historyFile()
+	if !ok {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if _, err := line.WriteHistory(&buf); err != nil {
+		return err
+	}
+
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	entries := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(entries) > historySize {
+		entries = entries[len(entries)-historySize:]
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(strings.Join(entries, "\n") + "\n")
+	return err
+}
@@ -1,100 +1,171 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"strings"
 
 	"bakku.dev/minimalisp"
-	"github.com/peterh/liner"
 )
 
+// Exit codes of the command.
+const (
+	exitUsage   = 64
+	exitSyntax  = 65
+	exitNoInput = 66
+	exitRuntime = 70
+)
+
+// syntaxError is a scan or parse error.
+type syntaxError struct {
+	err error
+}
+
+func (e *syntaxError) Error() string {
+	return e.err.Error()
+}
+
 func main() {
-	if len(os.Args) > 2 {
-		fmt.Println("Usage: mlisp [script]")
-	} else if len(os.Args) == 2 {
-		runScript(os.Args[1])
-	} else {
-		startRepl()
+	if len(os.Args) > 1 && os.Args[1] == "fmt" {
+		os.Exit(runFmt(os.Args[2:]))
 	}
-}
 
-func runScript(filename string) {
-	bytes, err := ioutil.ReadFile(filename)
-	if err != nil {
-		fmt.Println(fmt.Sprintf("could not read file: %v", err))
-		return
+	if len(os.Args) > 1 && os.Args[1] == "debug" {
+		os.Exit(runDebug(os.Args[2:]))
 	}
 
-	code := string(bytes)
+	os.Exit(run(os.Args[1:]))
+}
 
-	scanner := minimalisp.NewScanner(code, os.Stdout)
-	tokens, ok := scanner.Scan()
+// run interprets a script or an expression or starts the REPL. It returns
+// the exit code of the command.
+func run(args []string) int {
+	flags := flag.NewFlagSet("mlisp", flag.ContinueOnError)
+	expr := flags.String("e", "", "evaluate the expression instead of a script")
+	quiet := flags.Bool("quiet", false, "do not print the result")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: mlisp [--quiet] [script | - | -e expr] [args ...]")
+		fmt.Fprintln(flags.Output(), "       mlisp fmt [--check] [file ...]")
+		fmt.Fprintln(flags.Output(), "       mlisp debug script [args ...]")
+		flags.PrintDefaults()
+	}
 
-	if !ok {
-		return
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
 	}
 
-	parser := minimalisp.NewParser(tokens)
-	expressions, err := parser.Parse()
-	if err != nil {
-		fmt.Println(err)
-		return
+	evaluate := false
+	flags.Visit(func(f *flag.Flag) {
+		evaluate = evaluate || f.Name == "e"
+	})
+
+	if !evaluate && flags.NArg() == 0 {
+		return startRepl()
 	}
 
 	interpreter := minimalisp.NewInterpreter()
-	ret, err := interpreter.Interpret(expressions)
+
+	var ret interface{}
+	var err error
+
+	switch {
+	case evaluate:
+		defineArgs(interpreter, flags.Args())
+		ret, err = interpretSource(interpreter, "", *expr)
+	case flags.Arg(0) == "-":
+		defineArgs(interpreter, flags.Args()[1:])
+
+		src, readErr := ioutil.ReadAll(os.Stdin)
+		if readErr != nil {
+			fmt.Fprintf(os.Stderr, "could not read stdin: %v\n", readErr)
+			return exitNoInput
+		}
+
+		ret, err = interpretSource(interpreter, "", string(src))
+	default:
+		defineArgs(interpreter, flags.Args()[1:])
+
+		src, readErr := ioutil.ReadFile(flags.Arg(0))
+		if readErr != nil {
+			fmt.Fprintf(os.Stderr, "could not read file: %v\n", readErr)
+			return exitNoInput
+		}
+
+		ret, err = interpretSource(interpreter, flags.Arg(0), string(src))
+	}
+
 	if err != nil {
-		fmt.Println(err)
-	} else {
+		return exitCode(err)
+	}
+
+	if !*quiet {
+		if err := minimalisp.Realize(ret); err != nil {
+			return exitCode(err)
+		}
+
 		fmt.Println(fmt.Sprintf("=> %v", ret))
 	}
+
+	return 0
 }
 
-func startRepl() {
-	interpreter := minimalisp.NewInterpreter()
-	line := liner.NewLiner()
-	defer line.Close()
+// defineArgs passes the arguments of the command to the script as *args*.
+func defineArgs(interpreter *minimalisp.Interpreter, args []string) {
+	list := make([]interface{}, len(args))
+	for n, arg := range args {
+		list[n] = arg
+	}
 
-	line.SetCtrlCAborts(true)
+	_ = interpreter.Define("*args*", minimalisp.NewArrayList(list))
+}
 
-	for {
-		code, err := line.Prompt("> ")
-		if err == liner.ErrPromptAborted {
-			continue
-		}
+// exitCode prints an error unless the script called exit and returns the
+// exit code for it.
+func exitCode(err error) int {
+	if exit, ok := err.(*minimalisp.ExitError); ok {
+		return exit.Code
+	}
 
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
+	fmt.Fprintln(os.Stderr, err)
 
-		code = strings.TrimSpace(code)
-		if code == "exit" {
-			return
-		}
+	switch err.(type) {
+	case *syntaxError, *minimalisp.StaticError:
+		return exitSyntax
+	}
 
-		line.AppendHistory(code)
+	return exitRuntime
+}
 
-		scanner := minimalisp.NewScanner(code, os.Stdout)
-		tokens, ok := scanner.Scan()
-		if !ok {
-			continue
-		}
+// interpretFile reads, parses and interprets a file.
+func interpretFile(interpreter *minimalisp.Interpreter, filename string) (interface{}, error) {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not read file: %v", err)
+	}
 
-		parser := minimalisp.NewParser(tokens)
-		expressions, err := parser.Parse()
-		if err != nil {
-			fmt.Println(err)
-			continue
-		}
+	return interpretSource(interpreter, filename, string(src))
+}
 
-		ret, err := interpreter.Interpret(expressions)
-		if err != nil {
-			fmt.Println(err)
-		} else {
-			fmt.Println(fmt.Sprintf("=> %v", ret))
-		}
+// interpretSource parses and interprets source code. Without a filename
+// relative paths are resolved against the working directory.
+func interpretSource(interpreter *minimalisp.Interpreter, filename, src string) (interface{}, error) {
+	var errors strings.Builder
+
+	tokens, ok := minimalisp.NewScanner(src, &errors).Scan()
+	if !ok {
+		return nil, &syntaxError{fmt.Errorf("%s", strings.TrimSpace(errors.String()))}
 	}
+
+	expressions, err := minimalisp.NewParser(tokens).Parse()
+	if err != nil {
+		return nil, &syntaxError{err}
+	}
+
+	if filename == "" {
+		return interpreter.Interpret(expressions)
+	}
+
+	return interpreter.InterpretScript(filename, expressions)
 }
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"bakku.dev/minimalisp"
+	"github.com/peterh/liner"
+)
+
+// repl is an interactive session. The interpreter is replaced by :reset.
+type repl struct {
+	interpreter *minimalisp.Interpreter
+	// exit is set once the code called exit.
+	exit *minimalisp.ExitError
+}
+
+// command is a meta-command of the REPL like :load.
+type command struct {
+	args string
+	help string
+	run  func(r *repl, arg string)
+}
+
+var commands = map[string]command{
+	":doc":    {"name", "show the documentation of a variable", (*repl).doc},
+	":load":   {"file", "evaluate a file into the session", (*repl).load},
+	":env":    {"", "list the bindings defined in the session", (*repl).env},
+	":time":   {"expr", "evaluate an expression and show the time and allocations", (*repl).time},
+	":ast":    {"expr", "show the parsed expression tree", (*repl).ast},
+	":tokens": {"expr", "show the tokens of the scanner", (*repl).tokens},
+	":reset":  {"", "start over with a fresh interpreter", (*repl).reset},
+	":help":   {"", "list the commands", nil},
+}
+
+func newReplInterpreter() *minimalisp.Interpreter {
+	return minimalisp.NewInterpreter(minimalisp.WithRedefinition(func(name string) {
+		fmt.Printf("redefined %s\n", name)
+	}))
+}
+
+// startRepl runs the REPL and returns the exit code.
+func startRepl() int {
+	r := &repl{interpreter: newReplInterpreter()}
+	line := liner.NewLiner()
+	defer line.Close()
+
+	line.SetCtrlCAborts(true)
+	line.SetWordCompleter(r.complete)
+
+	loadHistory(line)
+	defer func() {
+		if err := saveHistory(line); err != nil {
+			fmt.Printf("could not save history: %v\n", err)
+		}
+	}()
+
+	// code collects the lines of an expression spanning multiple lines.
+	var code string
+
+	for {
+		prompt := "> "
+		if code != "" {
+			prompt = "... "
+		}
+
+		input, err := line.Prompt(prompt)
+		if err == liner.ErrPromptAborted {
+			code = ""
+			continue
+		}
+
+		if err != nil {
+			fmt.Println(err)
+			return 0
+		}
+
+		if code == "" {
+			input = strings.TrimSpace(input)
+			if input == "" {
+				continue
+			}
+
+			if input == "exit" {
+				return 0
+			}
+
+			if strings.HasPrefix(input, ":") {
+				line.AppendHistory(input)
+				r.command(input)
+			} else {
+				code = input
+			}
+		} else {
+			code += "\n" + input
+		}
+
+		if code != "" {
+			expressions, complete := parseInput(code)
+			if !complete {
+				continue
+			}
+
+			// Entries of the history file are single lines.
+			line.AppendHistory(strings.ReplaceAll(code, "\n", " "))
+			code = ""
+
+			if expressions != nil {
+				r.eval(expressions)
+			}
+		}
+
+		if r.exit != nil {
+			return r.exit.Code
+		}
+	}
+}
+
+// complete completes the name at the cursor with the names known to the
+// interpreter or the names of the commands.
+func (r *repl) complete(line string, pos int) (string, []string, string) {
+	// pos counts runes.
+	head, tail := string([]rune(line)[:pos]), string([]rune(line)[pos:])
+	start := strings.LastIndexAny(head, " \t()'\"") + 1
+	prefix := head[start:]
+
+	if start == 0 && strings.HasPrefix(prefix, ":") {
+		var names []string
+
+		for name := range commands {
+			if strings.HasPrefix(name, prefix) {
+				names = append(names, name+" ")
+			}
+		}
+
+		sort.Strings(names)
+		return "", names, tail
+	}
+
+	return head[:start], r.interpreter.Completions(prefix), tail
+}
+
+// parseInput parses the code entered into the REPL. complete is false if
+// the code ends in the middle of an expression or a string. Errors are
+// printed and return no expressions.
+func parseInput(code string) (expressions []minimalisp.Expression, complete bool) {
+	tokens, complete := scanInput(code)
+	if tokens == nil {
+		return nil, complete
+	}
+
+	parser := minimalisp.NewParser(tokens)
+	expressions, err := parser.Parse()
+	if _, ok := err.(*minimalisp.IncompleteInputError); ok {
+		return nil, false
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		return nil, true
+	}
+
+	return expressions, true
+}
+
+// scanInput scans the code entered into the REPL like parseInput.
+func scanInput(code string) (tokens []minimalisp.Token, complete bool) {
+	var errors bytes.Buffer
+
+	scanner := minimalisp.NewScanner(code, &errors)
+	tokens, ok := scanner.Scan()
+	if !ok {
+		if scanner.Incomplete() {
+			return nil, false
+		}
+
+		fmt.Print(errors.String())
+		return nil, true
+	}
+
+	return tokens, true
+}
+
+func (r *repl) eval(expressions []minimalisp.Expression) {
+	r.print(r.interpreter.Interpret(expressions))
+}
+
+// print prints the result of evaluating code or remembers that the code
+// called exit.
+func (r *repl) print(ret interface{}, err error) {
+	if exit, ok := err.(*minimalisp.ExitError); ok {
+		r.exit = exit
+	} else if err != nil {
+		fmt.Println(err)
+	} else if err := minimalisp.Realize(ret); err != nil {
+		r.print(nil, err)
+	} else {
+		fmt.Println(fmt.Sprintf("=> %v", ret))
+	}
+}
+
+// command runs a meta-command.
+func (r *repl) command(input string) {
+	name, arg := input, ""
+	if n := strings.IndexAny(input, " \t"); n >= 0 {
+		name, arg = input[:n], strings.TrimSpace(input[n:])
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Printf("unknown command %s, see :help\n", name)
+		return
+	}
+
+	if cmd.args != "" && arg == "" {
+		fmt.Printf("Usage: %s %s\n", name, cmd.args)
+		return
+	}
+
+	if name == ":help" {
+		help()
+		return
+	}
+
+	cmd.run(r, arg)
+}
+
+func help() {
+	var names []string
+
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		usage := strings.TrimSpace(name + " " + commands[name].args)
+		fmt.Printf("  %-14s %s\n", usage, commands[name].help)
+	}
+
+	fmt.Printf("  %-14s %s\n", "exit", "leave the REPL")
+}
+
+// parseArgument parses the argument of a command.
+func parseArgument(code string) ([]minimalisp.Expression, bool) {
+	expressions, complete := parseInput(code)
+	if !complete {
+		fmt.Println("incomplete input")
+	}
+
+	return expressions, expressions != nil
+}
+
+// doc prints the signature and description of a variable.
+func (r *repl) doc(name string) {
+	doc, err := r.interpreter.Doc(name)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(doc.Signature)
+
+	if doc.Description != "" {
+		fmt.Println("  " + doc.Description)
+	}
+}
+
+func (r *repl) load(filename string) {
+	r.print(interpretFile(r.interpreter, filename))
+}
+
+func (r *repl) env(string) {
+	globals := r.interpreter.Globals()
+
+	for _, name := range globals.Names() {
+		val, err := globals.Get(minimalisp.Token{TokenType: minimalisp.Identifier, Lexeme: name, Line: -1})
+		if err != nil {
+			continue
+		}
+
+		fmt.Printf("%s = %v\n", name, val)
+	}
+}
+
+func (r *repl) time(code string) {
+	exprs, ok := parseArgument(code)
+	if !ok {
+		return
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	ret, err := r.interpreter.Interpret(exprs)
+
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	// The result is printed afterwards, so that printing large or lazy
+	// results is not measured.
+	r.print(ret, err)
+	fmt.Printf("time: %v, allocations: %d (%d bytes)\n", elapsed, after.Mallocs-before.Mallocs, after.TotalAlloc-before.TotalAlloc)
+}
+
+func (r *repl) ast(code string) {
+	exprs, ok := parseArgument(code)
+	if !ok {
+		return
+	}
+
+	for _, expr := range exprs {
+		fmt.Print(minimalisp.DumpExpression(expr))
+	}
+}
+
+func (r *repl) tokens(code string) {
+	tokens, complete := scanInput(code)
+	if !complete {
+		fmt.Println("incomplete input")
+	}
+
+	for _, token := range tokens {
+		fmt.Printf("%4d  %-12s %s\n", token.Line, minimalisp.TokenName(token.TokenType), token.Lexeme)
+	}
+}
+
+func (r *repl) reset(string) {
+	r.interpreter = newReplInterpreter()
+	fmt.Println("started a fresh interpreter")
+}
@@ -1,5 +1,10 @@
 package minimalisp
 
+import (
+	"fmt"
+	"sort"
+)
+
 // First returns the first element of a list.
 type First struct{}
 
@@ -15,11 +20,12 @@ func (f *First) Call(line int, i *Interpreter, arguments []interface{}) (interfa
 		return nil, &executionError{line, "'first' is only defined for lists"}
 	}
 
-	if list.Len() == 0 {
-		return nil, nil
+	first, _, _, err := next(i, list)
+	if err != nil {
+		return nil, err
 	}
 
-	return list.First(), nil
+	return first, nil
 }
 
 func (f *First) String() string {
@@ -41,11 +47,12 @@ func (f *Rest) Call(line int, i *Interpreter, arguments []interface{}) (interfac
 		return nil, &executionError{line, "'rest' is only defined for lists"}
 	}
 
-	if list.Len() == 0 {
-		return nil, nil
+	_, rest, ok, err := next(i, list)
+	if err != nil || !ok {
+		return nil, err
 	}
 
-	return list.Rest(), nil
+	return rest, nil
 }
 
 func (f *Rest) String() string {
@@ -89,6 +96,15 @@ func (f *Len) Call(line int, i *Interpreter, arguments []interface{}) (interface
 		return nil, &executionError{line, "'len' is only defined for lists"}
 	}
 
+	if isLazy(list) {
+		elements, err := toSlice(i, list)
+		if err != nil {
+			return nil, err
+		}
+
+		return len(elements), nil
+	}
+
 	return list.Len(), nil
 }
 
@@ -96,37 +112,56 @@ func (f *Len) String() string {
 	return "<len>"
 }
 
-// Map applies a function to each element of a list.
+// Map applies a function to each element of one or more lists. If
+// multiple lists are given the function is called with one element of each
+// list and mapping stops at the end of the shortest list.
+// Usage:
+// (map + '(1 2) '(10 20)) => (11 22)
 type Map struct{}
 
-// Arity returns 2.
+// Arity returns infiniteArity as map accepts any amount of lists.
 func (f *Map) Arity() int {
-	return 2
+	return infiniteArity
 }
 
-// Call implements map for a list.
+// Call implements map for lists.
 func (f *Map) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
-	fun, ok := arguments[0].(Function)
-	if !ok {
-		return nil, &executionError{line, "<map> expects a function as first parameter"}
+	if len(arguments) < 2 {
+		return nil, &executionError{line, "<map> expects a function and at least one list"}
 	}
 
-	if fun.Arity() != 1 {
-		return nil, &executionError{line, "<map> expects a function which accepts one argument"}
+	fun, err := expectFunction(line, "map", arguments[0], len(arguments)-1)
+	if err != nil {
+		return nil, err
 	}
 
-	list, ok := arguments[1].(List)
-	if !ok {
-		return nil, &executionError{line, "<map> expects a list as second parameter"}
+	lists, lazy, err := expectLists(line, "map", arguments[1:])
+	if err != nil {
+		return nil, err
 	}
 
-	var mappedElements []interface{}
+	if lazy {
+		return lazyMap(line, i, fun, lists, 0, false), nil
+	}
 
-	restOfList := list
+	var mappedElements []interface{}
 
-	for restOfList.Len() > 0 {
+	for {
 		var args []interface{}
-		args = append(args, restOfList.First())
+
+		for n, list := range lists {
+			first, rest, ok, err := next(i, list)
+			if err != nil {
+				return nil, err
+			}
+
+			if !ok {
+				return NewArrayList(mappedElements), nil
+			}
+
+			args = append(args, first)
+			lists[n] = rest
+		}
 
 		newEl, err := fun.Call(line, i, args)
 		if err != nil {
@@ -134,15 +169,60 @@ func (f *Map) Call(line int, i *Interpreter, arguments []interface{}) (interface
 		}
 
 		mappedElements = append(mappedElements, newEl)
+	}
+}
 
-		restOfList = restOfList.Rest()
+func (f *Map) String() string {
+	return "<map>"
+}
+
+// MapIndexed applies a function to each element of a list and its index.
+// Usage:
+// (map-indexed (lambda (i el) (* i el)) '(1 2 3)) => (0 2 6)
+type MapIndexed struct{}
+
+// Arity returns 2.
+func (f *MapIndexed) Arity() int {
+	return 2
+}
+
+// Call implements map-indexed for a list.
+func (f *MapIndexed) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	fun, err := expectFunction(line, "map-indexed", arguments[0], 2)
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := arguments[1].(List)
+	if !ok {
+		return nil, &executionError{line, "<map-indexed> expects a list as second parameter"}
+	}
+
+	if isLazy(list) {
+		return lazyMap(line, i, fun, []List{list}, 0, true), nil
+	}
+
+	elements, err := toSlice(i, list)
+	if err != nil {
+		return nil, err
+	}
+
+	var mappedElements []interface{}
+
+	for n, el := range elements {
+		newEl, err := fun.Call(line, i, []interface{}{float64(n), el})
+		if err != nil {
+			return nil, err
+		}
+
+		mappedElements = append(mappedElements, newEl)
 	}
 
 	return NewArrayList(mappedElements), nil
 }
 
-func (f *Map) String() string {
-	return "<map>"
+func (f *MapIndexed) String() string {
+	return "<map-indexed>"
 }
 
 // Filter returns a new list with only the elements for which the given function returned true.
@@ -155,13 +235,9 @@ func (f *Filter) Arity() int {
 
 // Call implements filter for a list.
 func (f *Filter) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
-	fun, ok := arguments[0].(Function)
-	if !ok {
-		return nil, &executionError{line, "<filter> expects a function as first parameter"}
-	}
-
-	if fun.Arity() != 1 {
-		return nil, &executionError{line, "<filter> expects a function which accepts one argument"}
+	fun, err := expectFunction(line, "filter", arguments[0], 1)
+	if err != nil {
+		return nil, err
 	}
 
 	list, ok := arguments[1].(List)
@@ -169,6 +245,10 @@ func (f *Filter) Call(line int, i *Interpreter, arguments []interface{}) (interf
 		return nil, &executionError{line, "<filter> expects a list as second parameter"}
 	}
 
+	if isLazy(list) {
+		return lazyFilter(line, i, fun, list), nil
+	}
+
 	var filteredElements []interface{}
 
 	restOfList := list
@@ -195,3 +275,744 @@ func (f *Filter) Call(line int, i *Interpreter, arguments []interface{}) (interf
 func (f *Filter) String() string {
 	return "<filter>"
 }
+
+// Reduce combines all elements of a list into a single value using a function.
+// Usage:
+// (reduce + '(1 2 3)) => 6
+// (reduce + 10 '(1 2 3)) => 16
+type Reduce struct{}
+
+// Arity returns infiniteArity as the initial value is optional.
+func (f *Reduce) Arity() int {
+	return infiniteArity
+}
+
+// Call implements reduce for a list.
+func (f *Reduce) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	if len(arguments) != 2 && len(arguments) != 3 {
+		return nil, &executionError{line, "<reduce> expects a function, an optional initial value and a list"}
+	}
+
+	fun, err := expectFunction(line, "reduce", arguments[0], 2)
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := arguments[len(arguments)-1].(List)
+	if !ok {
+		return nil, &executionError{line, "<reduce> expects a list as last parameter"}
+	}
+
+	elements, err := toSlice(i, list)
+	if err != nil {
+		return nil, err
+	}
+
+	var acc interface{}
+
+	if len(arguments) == 3 {
+		acc = arguments[1]
+	} else {
+		if len(elements) == 0 {
+			return nil, nil
+		}
+
+		acc = elements[0]
+		elements = elements[1:]
+	}
+
+	for _, el := range elements {
+		if acc, err = fun.Call(line, i, []interface{}{acc, el}); err != nil {
+			return nil, err
+		}
+	}
+
+	return acc, nil
+}
+
+func (f *Reduce) String() string {
+	return "<reduce>"
+}
+
+// Range returns a list of numbers. Without arguments it returns an infinite
+// lazy sequence of numbers starting at 0.
+// Usage:
+// (range) => (0 1 2 ...)
+// (range 3) => (0 1 2)
+// (range 1 3) => (1 2)
+// (range 0 10 5) => (0 5)
+type Range struct{}
+
+// Arity returns infiniteArity as start and step are optional.
+func (f *Range) Arity() int {
+	return infiniteArity
+}
+
+// Call implements range.
+func (f *Range) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	if len(arguments) > 3 {
+		return nil, &executionError{line, "<range> expects an optional start, an end and an optional step"}
+	}
+
+	if len(arguments) == 0 {
+		return lazyRange(0, 1), nil
+	}
+
+	var nums []float64
+
+	for _, arg := range arguments {
+		num, ok := arg.(float64)
+		if !ok {
+			return nil, &executionError{line, "<range> is only defined for numbers"}
+		}
+
+		nums = append(nums, num)
+	}
+
+	var start, end, step float64 = 0, nums[0], 1
+
+	if len(nums) > 1 {
+		start, end = nums[0], nums[1]
+	}
+
+	if len(nums) > 2 {
+		step = nums[2]
+	}
+
+	if step == 0 {
+		return nil, &executionError{line, "<range> expects a step other than zero"}
+	}
+
+	var elements []interface{}
+
+	// Every element is computed from the start, as adding up the steps
+	// accumulates rounding errors.
+	for k := 0.0; ; k++ {
+		n := start + k*step
+		if (step > 0 && n >= end) || (step < 0 && n <= end) {
+			break
+		}
+
+		elements = append(elements, n)
+	}
+
+	return NewArrayList(elements), nil
+}
+
+func (f *Range) String() string {
+	return "<range>"
+}
+
+// Nth returns the element at a specific index of a list.
+type Nth struct{}
+
+// Arity returns 2.
+func (f *Nth) Arity() int {
+	return 2
+}
+
+// Call implements the extraction of the nth element.
+func (f *Nth) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	list, ok := arguments[0].(List)
+	if !ok {
+		return nil, &executionError{line, "<nth> expects a list as first parameter"}
+	}
+
+	n, err := expectIndex(line, "nth", arguments[1])
+	if err != nil {
+		return nil, err
+	}
+
+	rest := list
+
+	for index := 0; ; index++ {
+		first, r, ok, err := next(i, rest)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			return nil, &executionError{line, fmt.Sprintf("<nth> index %d out of bounds for list of length %d", n, index)}
+		}
+
+		if index == n {
+			return first, nil
+		}
+
+		rest = r
+	}
+}
+
+func (f *Nth) String() string {
+	return "<nth>"
+}
+
+// Last returns the last element of a list.
+type Last struct{}
+
+// Arity returns 1.
+func (f *Last) Arity() int {
+	return 1
+}
+
+// Call implements the extraction of the last element.
+func (f *Last) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	list, ok := arguments[0].(List)
+	if !ok {
+		return nil, &executionError{line, "<last> is only defined for lists"}
+	}
+
+	elements, err := toSlice(i, list)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(elements) == 0 {
+		return nil, nil
+	}
+
+	return elements[len(elements)-1], nil
+}
+
+func (f *Last) String() string {
+	return "<last>"
+}
+
+// Take returns the first n elements of a list.
+type Take struct{}
+
+// Arity returns 2.
+func (f *Take) Arity() int {
+	return 2
+}
+
+// Call implements take for a list.
+func (f *Take) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	n, err := expectIndex(line, "take", arguments[0])
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := arguments[1].(List)
+	if !ok {
+		return nil, &executionError{line, "<take> expects a list as second parameter"}
+	}
+
+	var elements []interface{}
+
+	for rest := list; len(elements) < n; {
+		first, r, ok, err := next(i, rest)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			break
+		}
+
+		elements = append(elements, first)
+		rest = r
+	}
+
+	return NewArrayList(elements), nil
+}
+
+func (f *Take) String() string {
+	return "<take>"
+}
+
+// Drop returns all except the first n elements of a list.
+type Drop struct{}
+
+// Arity returns 2.
+func (f *Drop) Arity() int {
+	return 2
+}
+
+// Call implements drop for a list.
+func (f *Drop) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	n, err := expectIndex(line, "drop", arguments[0])
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := arguments[1].(List)
+	if !ok {
+		return nil, &executionError{line, "<drop> expects a list as second parameter"}
+	}
+
+	if isLazy(list) {
+		return lazyDrop(line, i, n, list), nil
+	}
+
+	elements, err := toSlice(i, list)
+	if err != nil {
+		return nil, err
+	}
+
+	if n > len(elements) {
+		n = len(elements)
+	}
+
+	return NewArrayList(elements[n:]), nil
+}
+
+func (f *Drop) String() string {
+	return "<drop>"
+}
+
+// Reverse returns a list with all elements in reverse order.
+type Reverse struct{}
+
+// Arity returns 1.
+func (f *Reverse) Arity() int {
+	return 1
+}
+
+// Call implements reverse for a list.
+func (f *Reverse) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	list, ok := arguments[0].(List)
+	if !ok {
+		return nil, &executionError{line, "<reverse> is only defined for lists"}
+	}
+
+	elements, err := toSlice(i, list)
+	if err != nil {
+		return nil, err
+	}
+
+	reversed := make([]interface{}, len(elements))
+
+	for n, el := range elements {
+		reversed[len(elements)-1-n] = el
+	}
+
+	return NewArrayList(reversed), nil
+}
+
+func (f *Reverse) String() string {
+	return "<reverse>"
+}
+
+// Concat joins multiple lists into a single list.
+type Concat struct{}
+
+// Arity returns infiniteArity for concat.
+func (f *Concat) Arity() int {
+	return infiniteArity
+}
+
+// Call implements concat for lists.
+func (f *Concat) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	lists, lazy, err := expectLists(line, "concat", arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	if lazy {
+		return lazyConcat(line, i, lists), nil
+	}
+
+	var elements []interface{}
+
+	for _, list := range lists {
+		listElements, err := toSlice(i, list)
+		if err != nil {
+			return nil, err
+		}
+
+		elements = append(elements, listElements...)
+	}
+
+	return NewArrayList(elements), nil
+}
+
+func (f *Concat) String() string {
+	return "<concat>"
+}
+
+// Sort returns a sorted list. Numbers and strings are sorted in ascending
+// order unless a comparator is given which returns true if its first
+// argument should come before its second one.
+// Usage:
+// (sort '(3 1 2)) => (1 2 3)
+// (sort > '(3 1 2)) => (3 2 1)
+type Sort struct{}
+
+// Arity returns infiniteArity as the comparator is optional.
+func (f *Sort) Arity() int {
+	return infiniteArity
+}
+
+// Call implements sort for a list.
+func (f *Sort) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	if len(arguments) != 1 && len(arguments) != 2 {
+		return nil, &executionError{line, "<sort> expects an optional comparator and a list"}
+	}
+
+	list, ok := arguments[len(arguments)-1].(List)
+	if !ok {
+		return nil, &executionError{line, "<sort> expects a list as last parameter"}
+	}
+
+	less := defaultLess
+
+	if len(arguments) == 2 {
+		fun, err := expectFunction(line, "sort", arguments[0], 2)
+		if err != nil {
+			return nil, err
+		}
+
+		less = func(a, b interface{}) (bool, error) {
+			ret, err := fun.Call(line, i, []interface{}{a, b})
+			if err != nil {
+				return false, err
+			}
+
+			return isTruthy(ret), nil
+		}
+	}
+
+	elements, err := toSlice(i, list)
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := make([]interface{}, len(elements))
+	copy(sorted, elements)
+
+	var sortErr error
+
+	sort.SliceStable(sorted, func(a, b int) bool {
+		if sortErr != nil {
+			return false
+		}
+
+		ret, err := less(sorted[a], sorted[b])
+		if err != nil {
+			sortErr = err
+		}
+
+		return ret
+	})
+
+	if sortErr != nil {
+		switch sortErr.(type) {
+		case *executionError, *ExitError:
+			return nil, sortErr
+		}
+
+		return nil, &executionError{line, sortErr.Error()}
+	}
+
+	return NewArrayList(sorted), nil
+}
+
+func (f *Sort) String() string {
+	return "<sort>"
+}
+
+// Zip combines multiple lists into a list of lists where the nth list
+// contains the nth element of each given list.
+// Usage:
+// (zip '(1 2) '("a" "b")) => ((1 a) (2 b))
+type Zip struct{}
+
+// Arity returns infiniteArity for zip.
+func (f *Zip) Arity() int {
+	return infiniteArity
+}
+
+// Call implements zip for lists.
+func (f *Zip) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	if len(arguments) < 1 {
+		return nil, &executionError{line, "<zip> requires at least one argument"}
+	}
+
+	seqs, lazy, err := expectLists(line, "zip", arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	if lazy {
+		return lazyZip(line, i, seqs), nil
+	}
+
+	var lists [][]interface{}
+
+	for _, seq := range seqs {
+		elements, err := toSlice(i, seq)
+		if err != nil {
+			return nil, err
+		}
+
+		lists = append(lists, elements)
+	}
+
+	shortest := len(lists[0])
+
+	for _, l := range lists {
+		if len(l) < shortest {
+			shortest = len(l)
+		}
+	}
+
+	var zipped []interface{}
+
+	for n := 0; n < shortest; n++ {
+		var tuple []interface{}
+
+		for _, l := range lists {
+			tuple = append(tuple, l[n])
+		}
+
+		zipped = append(zipped, NewArrayList(tuple))
+	}
+
+	return NewArrayList(zipped), nil
+}
+
+func (f *Zip) String() string {
+	return "<zip>"
+}
+
+// Any returns true if the given function returns true for any element of a list.
+type Any struct{}
+
+// Arity returns 2.
+func (f *Any) Arity() int {
+	return 2
+}
+
+// Call implements any? for a list.
+func (f *Any) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	_, index, err := findIndex(line, i, "any?", arguments[0], arguments[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return index >= 0, nil
+}
+
+func (f *Any) String() string {
+	return "<any?>"
+}
+
+// Every returns true if the given function returns true for every element of a list.
+type Every struct{}
+
+// Arity returns 2.
+func (f *Every) Arity() int {
+	return 2
+}
+
+// Call implements every? for a list.
+func (f *Every) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	fun, err := expectFunction(line, "every?", arguments[0], 1)
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := arguments[1].(List)
+	if !ok {
+		return nil, &executionError{line, "<every?> expects a list as second parameter"}
+	}
+
+	for rest := list; ; {
+		first, r, ok, err := next(i, rest)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			return true, nil
+		}
+
+		ret, err := fun.Call(line, i, []interface{}{first})
+		if err != nil {
+			return nil, err
+		}
+
+		if !isTruthy(ret) {
+			return false, nil
+		}
+
+		rest = r
+	}
+}
+
+func (f *Every) String() string {
+	return "<every?>"
+}
+
+// Find returns the first element of a list for which the given function returns true.
+type Find struct{}
+
+// Arity returns 2.
+func (f *Find) Arity() int {
+	return 2
+}
+
+// Call implements find for a list.
+func (f *Find) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	el, _, err := findIndex(line, i, "find", arguments[0], arguments[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return el, nil
+}
+
+func (f *Find) String() string {
+	return "<find>"
+}
+
+// IndexOf returns the index of an element in a list or -1 if the list
+// does not contain the element.
+type IndexOf struct{}
+
+// Arity returns 2.
+func (f *IndexOf) Arity() int {
+	return 2
+}
+
+// Call implements index-of for a list.
+func (f *IndexOf) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	list, ok := arguments[0].(List)
+	if !ok {
+		return nil, &executionError{line, "<index-of> expects a list as first parameter"}
+	}
+
+	for n, rest := 0, list; ; n++ {
+		first, r, ok, err := next(i, rest)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			return float64(-1), nil
+		}
+
+		if equal(first, arguments[1]) {
+			return float64(n), nil
+		}
+
+		rest = r
+	}
+}
+
+func (f *IndexOf) String() string {
+	return "<index-of>"
+}
+
+// toSlice realizes all elements of a list for a task using only the List
+// interface.
+func toSlice(task *Interpreter, list List) ([]interface{}, error) {
+	var elements []interface{}
+
+	for rest := list; ; {
+		first, r, ok, err := next(task, rest)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			return elements, nil
+		}
+
+		elements = append(elements, first)
+		rest = r
+	}
+}
+
+// findIndex returns the first element for which fun returns true and its
+// index or -1 if there is no such element.
+func findIndex(line int, i *Interpreter, name string, fun interface{}, list interface{}) (interface{}, int, error) {
+	pred, err := expectFunction(line, name, fun, 1)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	l, ok := list.(List)
+	if !ok {
+		return nil, -1, &executionError{line, fmt.Sprintf("<%s> expects a list as second parameter", name)}
+	}
+
+	for n, rest := 0, l; ; n++ {
+		first, r, ok, err := next(i, rest)
+		if err != nil || !ok {
+			return nil, -1, err
+		}
+
+		ret, err := pred.Call(line, i, []interface{}{first})
+		if err != nil {
+			return nil, -1, err
+		}
+
+		if isTruthy(ret) {
+			return first, n, nil
+		}
+
+		rest = r
+	}
+}
+
+// expectLists makes sure that all arguments are lists and reports whether
+// any of them is lazy.
+func expectLists(line int, name string, arguments []interface{}) ([]List, bool, error) {
+	var lists []List
+	var lazy bool
+
+	for _, arg := range arguments {
+		list, ok := arg.(List)
+		if !ok {
+			return nil, false, &executionError{line, fmt.Sprintf("<%s> is only defined for lists", name)}
+		}
+
+		lazy = lazy || isLazy(list)
+		lists = append(lists, list)
+	}
+
+	return lists, lazy, nil
+}
+
+// expectFunction makes sure that a builtin received a function which
+// accepts the given amount of arguments.
+func expectFunction(line int, name string, arg interface{}, arity int) (Function, error) {
+	fun, ok := arg.(Function)
+	if !ok {
+		return nil, &executionError{line, fmt.Sprintf("<%s> expects a function as first parameter", name)}
+	}
+
+	if fun.Arity() != arity && fun.Arity() != infiniteArity {
+		return nil, &executionError{line, fmt.Sprintf("<%s> expects a function which accepts %d argument(s)", name, arity)}
+	}
+
+	return fun, nil
+}
+
+// expectIndex makes sure that a builtin received a non-negative whole number.
+func expectIndex(line int, name string, arg interface{}) (int, error) {
+	num, ok := arg.(float64)
+	if !ok || num < 0 || num != float64(int(num)) {
+		return 0, &executionError{line, fmt.Sprintf("<%s> expects a non-negative whole number", name)}
+	}
+
+	return int(num), nil
+}
+
+// defaultLess compares numbers and strings in ascending order.
+func defaultLess(a, b interface{}) (bool, error) {
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			return x < y, nil
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return x < y, nil
+		}
+	}
+
+	return false, fmt.Errorf("<sort> can only compare numbers or strings without a comparator")
+}
@@ -0,0 +1,75 @@
+package minimalisp_test
+
+import (
+	"testing"
+)
+
+func TestCollection_Reduce(t *testing.T) {
+	expectResult(t, "(reduce + '(1 2 3))", "6")
+	expectResult(t, "(reduce + 10 '(1 2 3))", "16")
+	expectResult(t, "(fold (lambda (acc x) (add acc (* x 2))) '() '(1 2))", "(2 4)")
+	expectResult(t, "(reduce + '())", "<nil>")
+}
+
+func TestCollection_Range(t *testing.T) {
+	expectResult(t, "(range 3)", "(0 1 2)")
+	expectResult(t, "(range 1 3)", "(1 2)")
+	expectResult(t, "(range 0 10 5)", "(0 5)")
+	expectResult(t, "(range 3 0 (- 0 1))", "(3 2 1)")
+	expectResult(t, "(len (range 0 1 0.1))", "10")
+	expectResult(t, "(nth (range 0 1 0.1) 9)", "0.9")
+	expectError(t, "(range 0 3 0)")
+}
+
+func TestCollection_Accessors(t *testing.T) {
+	expectResult(t, "(nth '(1 2 3) 1)", "2")
+	expectResult(t, "(last '(1 2 3))", "3")
+	expectResult(t, "(last '())", "<nil>")
+	expectResult(t, "(take 2 '(1 2 3))", "(1 2)")
+	expectResult(t, "(take 5 '(1 2 3))", "(1 2 3)")
+	expectResult(t, "(drop 2 '(1 2 3))", "(3)")
+	expectError(t, "(nth '(1 2 3) 3)")
+	expectError(t, "(take -1 '(1 2 3))")
+}
+
+func TestCollection_Reverse(t *testing.T) {
+	expectResult(t, "(reverse '(1 2 3))", "(3 2 1)")
+	expectResult(t, "(concat '(1) '() '(2 3))", "(1 2 3)")
+}
+
+func TestCollection_Sort(t *testing.T) {
+	expectResult(t, "(sort '(3 1 2))", "(1 2 3)")
+	expectResult(t, "(sort '(\"b\" \"a\"))", "(a b)")
+	expectResult(t, "(sort > '(3 1 2))", "(3 2 1)")
+	expectResult(t, "(sort (lambda (a b) (< (first a) (first b))) '('(2 1) '(1)))", "((1) (2 1))")
+	expectError(t, "(sort '(1 \"a\"))")
+}
+
+func TestCollection_Zip(t *testing.T) {
+	expectResult(t, "(zip '(1 2 3) '(\"a\" \"b\"))", "((1 a) (2 b))")
+}
+
+func TestCollection_Predicates(t *testing.T) {
+	expectResult(t, "(any? (lambda (x) (> x 2)) '(1 2 3))", "true")
+	expectResult(t, "(any? (lambda (x) (> x 3)) '(1 2 3))", "false")
+	expectResult(t, "(every? (lambda (x) (> x 0)) '(1 2 3))", "true")
+	expectResult(t, "(every? (lambda (x) (> x 1)) '(1 2 3))", "false")
+	expectResult(t, "(find (lambda (x) (> x 1)) '(1 2 3))", "2")
+	expectResult(t, "(find (lambda (x) (> x 3)) '(1 2 3))", "<nil>")
+	expectResult(t, "(index-of '(1 2 3) 3)", "2")
+	expectResult(t, "(index-of '(1 2 3) 4)", "-1")
+}
+
+func TestCollection_Map(t *testing.T) {
+	expectResult(t, "(map (lambda (x) (* x 2)) '(1 2 3))", "(2 4 6)")
+	expectResult(t, "(map + '(1 2 3) '(10 20))", "(11 22)")
+	expectResult(t, "(map (lambda (a b c) (+ a b c)) '(1 2) '(1 2) '(1 2))", "(3 6)")
+	expectResult(t, "(map println '())", "()")
+	expectResult(t, "(map-indexed (lambda (i el) (* i el)) '(1 2 3))", "(0 2 6)")
+	expectError(t, "(map (lambda (x) x) '(1) '(2))")
+}
+
+func TestCollection_Filter(t *testing.T) {
+	expectResult(t, "(filter (lambda (x) (> x 1)) '(1 2 3))", "(2 3)")
+	expectError(t, "(filter (lambda (a b) a) '(1 2))")
+}
@@ -0,0 +1,666 @@
+package minimalisp
+
+import "fmt"
+
+// opcode is an instruction of the bytecode virtual machine. The operands
+// of an instruction follow it as 16 bit big endian numbers.
+type opcode byte
+
+const (
+	opConstant       opcode = iota // index: pushes a constant
+	opNil                          // pushes nil
+	opPop                          // drops the top of the stack
+	opDup                          // duplicates the top of the stack
+	opGetLocal                     // depth, slot: pushes a local variable
+	opSetLocal                     // slot: pops a value into a slot of the current frame
+	opGetGlobal                    // name: pushes a global variable
+	opDefine                       // name: defines a global with the value on top of the stack
+	opDefineDynamic                // name: defines a dynamic variable with the value on top of the stack
+	opJump                         // address
+	opJumpIfFalse                  // address: pops the condition
+	opCallable                     // name: checks that the top of the stack is a function
+	opCall                         // argc: calls the function below the arguments
+	opList                         // n: pops n values and pushes a list of them
+	opClosure                      // prototype: pushes a function closing over the current frame
+	opEval                         // expression: evaluates an expression with the tree-walking interpreter
+	opListBegin                    // pattern, fail: starts destructuring a list
+	opListNext                     // pattern, n, fail: pushes the next element of a destructured list
+	opListRest                     // pushes the remaining elements of a destructured list
+	opListEnd                      // pattern, fail: checks that no elements remain
+	opMatchLiteral                 // pattern, fail: pops a value and compares it with a literal
+	opGuard                        // pattern, fail: pops a predicate and calls it with the top of the stack
+	opMark                         // remembers the height of the stack
+	opUnmark                       // forgets the remembered height of the stack
+	opRestore                      // resets the stack to the remembered height
+	opNoMatch                      // reports that no clause of a match matched
+	opCheckChannel                 // kind: checks that the top of the stack is a channel
+	opSelect                       // table: runs a select and jumps to the chosen clause
+	opGetDynamic                   // name: pushes a dynamic variable itself instead of its value
+	opParameterize                 // n: binds n dynamic variables to values
+	opUnparameterize               // restores the dynamic bindings before the last opParameterize
+	opReturn                       // returns the top of the stack
+)
+
+// noJump is used as fail address by patterns which report an error instead
+// of jumping when a value does not match.
+const noJump = 0xffff
+
+// chunk is a compiled piece of code.
+type chunk struct {
+	code      []byte
+	lines     []int
+	constants []interface{}
+}
+
+// prototype is a compiled function. Closures are created from it at runtime.
+type prototype struct {
+	name   string
+	params []Token
+	// rest is the slot of the rest parameter or -1.
+	rest  int
+	slots int
+	doc   string
+	// body is kept to describe the function in its documentation.
+	body Expression
+	chunk
+}
+
+// selectTable holds the addresses of the clauses of a select.
+type selectTable struct {
+	expr    *SelectExpr
+	targets []int
+}
+
+// compiler translates resolved expressions into bytecode. Each function is
+// compiled by its own compiler. All local variables of a function, including
+// those of nested lets and patterns, get a slot in the frame of the function.
+type compiler struct {
+	enclosing *compiler
+	proto     *prototype
+	scopes    []map[string]int
+	line      int
+}
+
+// compileScript compiles resolved top-level expressions into a prototype
+// which returns the value of the last expression.
+func compileScript(expressions []Expression) (*prototype, error) {
+	c := &compiler{proto: &prototype{name: "script", rest: -1}}
+	c.beginScope()
+
+	if len(expressions) == 0 {
+		c.emit(opNil)
+	}
+
+	for n, expr := range expressions {
+		if n > 0 {
+			c.emit(opPop)
+		}
+
+		if err := c.compile(expr); err != nil {
+			return nil, err
+		}
+	}
+
+	c.emit(opReturn)
+
+	return c.proto, nil
+}
+
+func (c *compiler) compile(expr Expression) error {
+	_, err := expr.Accept(c)
+	return err
+}
+
+func (c *compiler) emit(op opcode, operands ...int) {
+	c.proto.code = append(c.proto.code, byte(op))
+	c.proto.lines = append(c.proto.lines, c.line)
+
+	for _, operand := range operands {
+		c.proto.code = append(c.proto.code, byte(operand>>8), byte(operand))
+		c.proto.lines = append(c.proto.lines, c.line, c.line)
+	}
+}
+
+// emitJump emits an instruction whose last operand is an address which is
+// patched later and returns the position of that operand.
+func (c *compiler) emitJump(op opcode, operands ...int) int {
+	c.emit(op, append(operands, noJump)...)
+	return len(c.proto.code) - 2
+}
+
+// patch sets the address operand at pos to the current end of the code.
+func (c *compiler) patch(pos int) error {
+	target := len(c.proto.code)
+	if target >= noJump {
+		return &executionError{c.line, fmt.Sprintf("Function %s is too large to compile", c.proto.name)}
+	}
+
+	c.proto.code[pos] = byte(target >> 8)
+	c.proto.code[pos+1] = byte(target)
+
+	return nil
+}
+
+func (c *compiler) patchAll(positions []int) error {
+	for _, pos := range positions {
+		if err := c.patch(pos); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *compiler) constant(value interface{}) (int, error) {
+	if len(c.proto.constants) >= noJump {
+		return 0, &executionError{c.line, fmt.Sprintf("Function %s has too many constants", c.proto.name)}
+	}
+
+	c.proto.constants = append(c.proto.constants, value)
+	return len(c.proto.constants) - 1, nil
+}
+
+// emitConstant emits an instruction whose first operand is a constant.
+func (c *compiler) emitConstant(op opcode, value interface{}, operands ...int) error {
+	idx, err := c.constant(value)
+	if err != nil {
+		return err
+	}
+
+	c.emit(op, append([]int{idx}, operands...)...)
+	return nil
+}
+
+func (c *compiler) beginScope() {
+	c.scopes = append(c.scopes, make(map[string]int))
+}
+
+func (c *compiler) endScope() {
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+// declare allocates a slot for a local variable in the innermost scope.
+func (c *compiler) declare(name Token) (int, error) {
+	if c.proto.slots >= noJump {
+		return 0, &executionError{name.Line, fmt.Sprintf("Function %s has too many local variables", c.proto.name)}
+	}
+
+	slot := c.proto.slots
+	c.scopes[len(c.scopes)-1][name.Lexeme] = slot
+	c.proto.slots++
+
+	return slot, nil
+}
+
+// local finds the frame and the slot of a local variable.
+func (c *compiler) local(name string) (depth, slot int, ok bool) {
+	for fc := c; fc != nil; fc, depth = fc.enclosing, depth+1 {
+		for n := len(fc.scopes) - 1; n >= 0; n-- {
+			if slot, ok := fc.scopes[n][name]; ok {
+				return depth, slot, true
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+// variable emits the lookup of a variable, which was already resolved to be
+// either local or global.
+func (c *compiler) variable(name Token) error {
+	c.line = name.Line
+
+	if depth, slot, ok := c.local(name.Lexeme); ok {
+		c.emit(opGetLocal, depth, slot)
+		return nil
+	}
+
+	return c.emitConstant(opGetGlobal, name)
+}
+
+// function compiles the parameters and the body of a function into a
+// prototype and emits the creation of a closure.
+func (c *compiler) function(name string, params []Token, doc string, body Expression) error {
+	fc := &compiler{enclosing: c, proto: &prototype{name: name, params: params, rest: -1, doc: doc, body: body}, line: c.line}
+	fc.beginScope()
+
+	for _, param := range params {
+		if param.TokenType == AmpRest {
+			fc.proto.rest = fc.proto.slots
+			continue
+		}
+
+		if _, err := fc.declare(param); err != nil {
+			return err
+		}
+	}
+
+	if err := fc.compile(body); err != nil {
+		return err
+	}
+
+	fc.emit(opReturn)
+
+	return c.emitConstant(opClosure, fc.proto)
+}
+
+// pattern emits code which binds the value on top of the stack to the
+// variables of a pattern. If fails is nil, values which do not match are
+// reported as errors. Otherwise the positions of the jumps taken on a
+// mismatch are added to fails.
+func (c *compiler) pattern(pattern Pattern, fails *[]int) error {
+	fail := func(op opcode, operands ...int) error {
+		idx, err := c.constant(pattern)
+		if err != nil {
+			return err
+		}
+
+		if fails == nil {
+			c.emit(op, append(append([]int{idx}, operands...), noJump)...)
+		} else {
+			*fails = append(*fails, c.emitJump(op, append([]int{idx}, operands...)...))
+		}
+
+		return nil
+	}
+
+	switch p := pattern.(type) {
+	case *WildcardPattern:
+		c.emit(opPop)
+	case *BindingPattern:
+		slot, err := c.declare(p.Name)
+		if err != nil {
+			return err
+		}
+
+		c.emit(opSetLocal, slot)
+	case *LiteralPattern:
+		return fail(opMatchLiteral)
+	case *GuardPattern:
+		if fails == nil {
+			return &executionError{c.line, fmt.Sprintf("Cannot destructure with guard pattern %s", p)}
+		}
+
+		if err := c.compile(p.Predicate); err != nil {
+			return err
+		}
+
+		if err := fail(opGuard); err != nil {
+			return err
+		}
+
+		return c.pattern(p.Pattern, fails)
+	case *ListPattern:
+		if err := fail(opListBegin); err != nil {
+			return err
+		}
+
+		for n, el := range p.Elements {
+			if err := fail(opListNext, n); err != nil {
+				return err
+			}
+
+			if err := c.pattern(el, fails); err != nil {
+				return err
+			}
+		}
+
+		if p.Rest != nil {
+			c.emit(opListRest)
+			return c.pattern(p.Rest, fails)
+		}
+
+		return fail(opListEnd)
+	}
+
+	return nil
+}
+
+func (c *compiler) visitLiteralExpr(literalExpr *LiteralExpr) (interface{}, error) {
+	if literalExpr.Value == nil {
+		c.emit(opNil)
+		return nil, nil
+	}
+
+	return nil, c.emitConstant(opConstant, literalExpr.Value)
+}
+
+func (c *compiler) visitDefvarExpr(defvarExpr *DefvarExpr) (interface{}, error) {
+	if err := c.compile(defvarExpr.Initializer); err != nil {
+		return nil, err
+	}
+
+	c.line = defvarExpr.Name.Line
+	return nil, c.emitConstant(opDefine, defvarExpr.Name)
+}
+
+func (c *compiler) visitDefparameterExpr(defparameterExpr *DefparameterExpr) (interface{}, error) {
+	if err := c.compile(defparameterExpr.Initializer); err != nil {
+		return nil, err
+	}
+
+	c.line = defparameterExpr.Name.Line
+	return nil, c.emitConstant(opDefineDynamic, defparameterExpr.Name)
+}
+
+func (c *compiler) visitVarExpr(varExpr *VarExpr) (interface{}, error) {
+	c.line = varExpr.Name.Line
+	return nil, c.emitConstant(opGetGlobal, varExpr.Name)
+}
+
+func (c *compiler) visitLocalExpr(localExpr *LocalExpr) (interface{}, error) {
+	return nil, c.variable(localExpr.Name)
+}
+
+func (c *compiler) visitIfExpr(ifExpr *IfExpr) (interface{}, error) {
+	if err := c.compile(ifExpr.Condition); err != nil {
+		return nil, err
+	}
+
+	elseJump := c.emitJump(opJumpIfFalse)
+
+	if err := c.compile(ifExpr.ThenBranch); err != nil {
+		return nil, err
+	}
+
+	endJump := c.emitJump(opJump)
+
+	if err := c.patch(elseJump); err != nil {
+		return nil, err
+	}
+
+	if err := c.compile(ifExpr.ElseBranch); err != nil {
+		return nil, err
+	}
+
+	return nil, c.patch(endJump)
+}
+
+func (c *compiler) visitDefunExpr(defunExpr *DefunExpr) (interface{}, error) {
+	c.line = defunExpr.Name.Line
+
+	if err := c.function(defunExpr.Name.Lexeme, defunExpr.Params, defunExpr.Doc, defunExpr.Body); err != nil {
+		return nil, err
+	}
+
+	c.line = defunExpr.Name.Line
+	return nil, c.emitConstant(opDefine, defunExpr.Name)
+}
+
+func (c *compiler) visitFuncCallExpr(funcCallExpr *FuncCallExpr) (interface{}, error) {
+	c.line = funcCallExpr.Name.Line
+
+	if err := c.emitConstant(opGetGlobal, funcCallExpr.Name); err != nil {
+		return nil, err
+	}
+
+	return nil, c.call(funcCallExpr.Name, funcCallExpr.Arguments)
+}
+
+func (c *compiler) visitLocalCallExpr(localCallExpr *LocalCallExpr) (interface{}, error) {
+	if err := c.variable(localCallExpr.Name); err != nil {
+		return nil, err
+	}
+
+	return nil, c.call(localCallExpr.Name, localCallExpr.Arguments)
+}
+
+// call emits the call of the function on top of the stack.
+func (c *compiler) call(name Token, arguments []Expression) error {
+	if err := c.emitConstant(opCallable, name); err != nil {
+		return err
+	}
+
+	for _, arg := range arguments {
+		if err := c.compile(arg); err != nil {
+			return err
+		}
+	}
+
+	c.line = name.Line
+	c.emit(opCall, len(arguments))
+
+	return nil
+}
+
+func (c *compiler) visitListExpr(listExpr *ListExpr) (interface{}, error) {
+	for _, element := range listExpr.Elements {
+		if err := c.compile(element); err != nil {
+			return nil, err
+		}
+	}
+
+	c.emit(opList, len(listExpr.Elements))
+	return nil, nil
+}
+
+func (c *compiler) visitLetExpr(letExpr *LetExpr) (interface{}, error) {
+	for _, value := range letExpr.Values {
+		if err := c.compile(value); err != nil {
+			return nil, err
+		}
+	}
+
+	c.beginScope()
+	defer c.endScope()
+
+	var slots []int
+
+	for _, name := range letExpr.Names {
+		slot, err := c.declare(name)
+		if err != nil {
+			return nil, err
+		}
+
+		slots = append(slots, slot)
+	}
+
+	for n := len(slots) - 1; n >= 0; n-- {
+		c.emit(opSetLocal, slots[n])
+	}
+
+	return nil, c.compile(letExpr.Body)
+}
+
+func (c *compiler) visitLetStarExpr(letStarExpr *LetStarExpr) (interface{}, error) {
+	for n, name := range letStarExpr.Names {
+		if err := c.compile(letStarExpr.Values[n]); err != nil {
+			return nil, err
+		}
+
+		c.beginScope()
+		defer c.endScope()
+
+		slot, err := c.declare(name)
+		if err != nil {
+			return nil, err
+		}
+
+		c.emit(opSetLocal, slot)
+	}
+
+	return nil, c.compile(letStarExpr.Body)
+}
+
+func (c *compiler) visitLetrecExpr(letrecExpr *LetrecExpr) (interface{}, error) {
+	c.beginScope()
+	defer c.endScope()
+
+	var slots []int
+
+	for _, name := range letrecExpr.Names {
+		slot, err := c.declare(name)
+		if err != nil {
+			return nil, err
+		}
+
+		slots = append(slots, slot)
+	}
+
+	for n, value := range letrecExpr.Values {
+		if err := c.compile(value); err != nil {
+			return nil, err
+		}
+
+		c.emit(opSetLocal, slots[n])
+	}
+
+	return nil, c.compile(letrecExpr.Body)
+}
+
+func (c *compiler) visitLambdaExpr(lambdaExpr *LambdaExpr) (interface{}, error) {
+	return nil, c.function("lambda", lambdaExpr.Params, "", lambdaExpr.Body)
+}
+
+func (c *compiler) visitDestructureExpr(destructureExpr *DestructureExpr) (interface{}, error) {
+	if err := c.compile(destructureExpr.Value); err != nil {
+		return nil, err
+	}
+
+	c.beginScope()
+	defer c.endScope()
+
+	c.line = destructureExpr.Pattern.line()
+
+	if err := c.pattern(destructureExpr.Pattern, nil); err != nil {
+		return nil, err
+	}
+
+	return nil, c.compile(destructureExpr.Body)
+}
+
+func (c *compiler) visitModuleExpr(moduleExpr *ModuleExpr) (interface{}, error) {
+	return nil, c.emitConstant(opEval, moduleExpr)
+}
+
+func (c *compiler) visitRequireExpr(requireExpr *RequireExpr) (interface{}, error) {
+	return nil, c.emitConstant(opEval, requireExpr)
+}
+
+func (c *compiler) visitDefstructExpr(defstructExpr *DefstructExpr) (interface{}, error) {
+	return nil, c.emitConstant(opEval, defstructExpr)
+}
+
+func (c *compiler) visitMatchExpr(matchExpr *MatchExpr) (interface{}, error) {
+	if err := c.compile(matchExpr.Value); err != nil {
+		return nil, err
+	}
+
+	var ends []int
+
+	for _, clause := range matchExpr.Clauses {
+		c.line = matchExpr.Keyword.Line
+		c.emit(opMark)
+		c.emit(opDup)
+		c.beginScope()
+
+		var fails []int
+
+		if err := c.pattern(clause.Pattern, &fails); err != nil {
+			return nil, err
+		}
+
+		c.emit(opUnmark)
+		c.emit(opPop)
+
+		if err := c.compile(clause.Body); err != nil {
+			return nil, err
+		}
+
+		c.endScope()
+		ends = append(ends, c.emitJump(opJump))
+
+		if err := c.patchAll(fails); err != nil {
+			return nil, err
+		}
+
+		c.emit(opRestore)
+	}
+
+	c.line = matchExpr.Keyword.Line
+	c.emit(opNoMatch)
+
+	return nil, c.patchAll(ends)
+}
+
+func (c *compiler) visitSelectExpr(selectExpr *SelectExpr) (interface{}, error) {
+	for _, clause := range selectExpr.Clauses {
+		if clause.Kind.Lexeme == "default" {
+			continue
+		}
+
+		if err := c.compile(clause.Channel); err != nil {
+			return nil, err
+		}
+
+		c.line = clause.Kind.Line
+
+		if err := c.emitConstant(opCheckChannel, clause.Kind); err != nil {
+			return nil, err
+		}
+
+		if clause.Kind.Lexeme == "send" {
+			if err := c.compile(clause.Value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	table := &selectTable{expr: selectExpr}
+
+	c.line = selectExpr.Keyword.Line
+
+	if err := c.emitConstant(opSelect, table); err != nil {
+		return nil, err
+	}
+
+	var ends []int
+
+	for _, clause := range selectExpr.Clauses {
+		table.targets = append(table.targets, len(c.proto.code))
+		c.beginScope()
+
+		if clause.Kind.Lexeme == "recv" {
+			slot, err := c.declare(clause.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			c.emit(opSetLocal, slot)
+		} else {
+			c.emit(opPop)
+		}
+
+		if err := c.compile(clause.Body); err != nil {
+			return nil, err
+		}
+
+		c.endScope()
+		ends = append(ends, c.emitJump(opJump))
+	}
+
+	return nil, c.patchAll(ends)
+}
+
+func (c *compiler) visitParameterizeExpr(parameterizeExpr *ParameterizeExpr) (interface{}, error) {
+	for n, name := range parameterizeExpr.Names {
+		c.line = name.Line
+
+		if err := c.emitConstant(opGetDynamic, name); err != nil {
+			return nil, err
+		}
+
+		if err := c.compile(parameterizeExpr.Values[n]); err != nil {
+			return nil, err
+		}
+	}
+
+	c.emit(opParameterize, len(parameterizeExpr.Names))
+
+	if err := c.compile(parameterizeExpr.Body); err != nil {
+		return nil, err
+	}
+
+	c.emit(opUnparameterize)
+	return nil, nil
+}
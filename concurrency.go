@@ -0,0 +1,295 @@
+package minimalisp
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Channel is a Go channel which tasks use to communicate with each other.
+type Channel struct {
+	ch chan interface{}
+	// err is set before the channel is closed if the task producing the
+	// values of the channel failed.
+	err error
+}
+
+// NewChannel is a factory function to create a new channel with a buffer
+// for size values.
+func NewChannel(size int) *Channel {
+	return &Channel{ch: make(chan interface{}, size)}
+}
+
+// Send sends a value and blocks until it was received or buffered.
+func (c *Channel) Send(line int, value interface{}) (err error) {
+	defer func() {
+		if recover() != nil {
+			err = &executionError{line, "Cannot send on a closed channel"}
+		}
+	}()
+
+	c.ch <- value
+	return nil
+}
+
+// Receive blocks until a value is available. Receiving from a closed
+// channel returns nil or the error of the task which produced the values.
+func (c *Channel) Receive() (interface{}, error) {
+	val, ok := <-c.ch
+	if !ok {
+		return nil, c.err
+	}
+
+	return val, nil
+}
+
+// Close closes the channel. Receivers get the remaining values and then nil.
+func (c *Channel) Close(line int) (err error) {
+	defer func() {
+		if recover() != nil {
+			err = &executionError{line, "Cannot close a closed channel"}
+		}
+	}()
+
+	close(c.ch)
+	return nil
+}
+
+func (c *Channel) String() string {
+	return "<chan>"
+}
+
+func (i *Interpreter) visitSelectExpr(selectExpr *SelectExpr) (interface{}, error) {
+	var cases []reflect.SelectCase
+	var channels []*Channel
+
+	for _, clause := range selectExpr.Clauses {
+		if clause.Kind.Lexeme == "default" {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+			channels = append(channels, nil)
+			continue
+		}
+
+		val, err := clause.Channel.Accept(i)
+		if err != nil {
+			return nil, err
+		}
+
+		ch, ok := val.(*Channel)
+		if !ok {
+			return nil, &executionError{clause.Kind.Line, fmt.Sprintf("Cannot %s with %v: expected a channel", clause.Kind.Lexeme, val)}
+		}
+
+		channels = append(channels, ch)
+
+		if clause.Kind.Lexeme == "recv" {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch.ch)})
+			continue
+		}
+
+		sent, err := clause.Value.Accept(i)
+		if err != nil {
+			return nil, err
+		}
+
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(ch.ch), Send: reflect.ValueOf(&sent).Elem()})
+	}
+
+	chosen, received, ok := selectCase(cases)
+	if chosen < 0 {
+		return nil, &executionError{selectExpr.Keyword.Line, "Cannot send on a closed channel"}
+	}
+
+	clause := selectExpr.Clauses[chosen]
+	env := NewEnvironmentWithEnclosing(i.current)
+
+	if clause.Kind.Lexeme == "recv" {
+		var val interface{}
+
+		if ok {
+			val = received.Interface()
+		} else if err := channels[chosen].err; err != nil {
+			return nil, err
+		}
+
+		if err := env.Define(clause.Name, val); err != nil {
+			return nil, err
+		}
+	}
+
+	return i.execute(clause.Body, env)
+}
+
+// selectCase runs a Go select and returns -1 if a value was sent on a closed
+// channel.
+func selectCase(cases []reflect.SelectCase) (chosen int, received reflect.Value, ok bool) {
+	defer func() {
+		if recover() != nil {
+			chosen = -1
+		}
+	}()
+
+	return reflect.Select(cases)
+}
+
+// Spawn calls a function with the given arguments on a new goroutine and
+// returns a channel which receives its result. If the function fails,
+// receiving from the channel returns the error.
+// Usage:
+// (recv (spawn + 1 2)) => 3
+type Spawn struct{}
+
+// Arity returns infiniteArity as the arguments of the function are passed along.
+func (f *Spawn) Arity() int {
+	return infiniteArity
+}
+
+// Call implements spawn.
+func (f *Spawn) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	if len(arguments) == 0 {
+		return nil, &executionError{line, "<spawn> expects a function and its arguments"}
+	}
+
+	fun, err := expectFunction(line, "spawn", arguments[0], len(arguments)-1)
+	if err != nil {
+		return nil, err
+	}
+
+	result := NewChannel(1)
+	task := i.fork()
+
+	go func() {
+		ret, err := fun.Call(line, task, arguments[1:])
+		if err != nil {
+			result.err = err
+		} else {
+			result.ch <- ret
+		}
+
+		close(result.ch)
+	}()
+
+	return result, nil
+}
+
+func (f *Spawn) String() string {
+	return "<spawn>"
+}
+
+// Chan creates a channel with an optional buffer size.
+// Usage:
+// (chan) => unbuffered channel
+// (chan 10) => channel buffering 10 values
+type Chan struct{}
+
+// Arity returns infiniteArity as the buffer size is optional.
+func (f *Chan) Arity() int {
+	return infiniteArity
+}
+
+// Call implements chan.
+func (f *Chan) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	switch len(arguments) {
+	case 0:
+		return NewChannel(0), nil
+	case 1:
+		size, err := expectIndex(line, "chan", arguments[0])
+		if err != nil {
+			return nil, err
+		}
+
+		return NewChannel(size), nil
+	}
+
+	return nil, &executionError{line, "<chan> expects an optional buffer size"}
+}
+
+func (f *Chan) String() string {
+	return "<chan>"
+}
+
+// Send sends a value on a channel and returns the value.
+// Usage:
+// (send ch 1) => 1
+type Send struct{}
+
+// Arity returns 2.
+func (f *Send) Arity() int {
+	return 2
+}
+
+// Call implements send.
+func (f *Send) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	ch, err := expectChannel(line, "send", arguments[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ch.Send(line, arguments[1]); err != nil {
+		return nil, err
+	}
+
+	return arguments[1], nil
+}
+
+func (f *Send) String() string {
+	return "<send>"
+}
+
+// Recv receives a value from a channel. It returns nil once the channel is
+// closed.
+// Usage:
+// (recv ch)
+type Recv struct{}
+
+// Arity returns 1.
+func (f *Recv) Arity() int {
+	return 1
+}
+
+// Call implements recv.
+func (f *Recv) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	ch, err := expectChannel(line, "recv", arguments[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return ch.Receive()
+}
+
+func (f *Recv) String() string {
+	return "<recv>"
+}
+
+// Close closes a channel.
+// Usage:
+// (close ch)
+type Close struct{}
+
+// Arity returns 1.
+func (f *Close) Arity() int {
+	return 1
+}
+
+// Call implements close.
+func (f *Close) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	ch, err := expectChannel(line, "close", arguments[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, ch.Close(line)
+}
+
+func (f *Close) String() string {
+	return "<close>"
+}
+
+// expectChannel makes sure that a builtin received a channel.
+func expectChannel(line int, name string, arg interface{}) (*Channel, error) {
+	ch, ok := arg.(*Channel)
+	if !ok {
+		return nil, &executionError{line, fmt.Sprintf("<%s> expects a channel as first parameter", name)}
+	}
+
+	return ch, nil
+}
@@ -0,0 +1,143 @@
+package minimalisp_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	. "bakku.dev/minimalisp"
+)
+
+func TestConcurrency_SpawnReturnsResultOnChannel(t *testing.T) {
+	expectResult(t, "(recv (spawn + 1 2))", "3")
+	expectResult(t, "(recv (spawn (lambda () (map (lambda (x) (* x x)) '(1 2 3)))))", "(1 4 9)")
+	expectResult(t, "(defvar ch (spawn (lambda () 1))) (recv ch) (recv ch)", "<nil>")
+}
+
+func TestConcurrency_SpawnPropagatesErrors(t *testing.T) {
+	err := expectError(t, "(recv (spawn / 1 0))")
+	if !strings.Contains(err.Error(), "Division by zero") {
+		t.Fatalf("Expected division by zero error, got %v", err)
+	}
+
+	expectError(t, "(spawn (lambda (x) x))")
+	expectError(t, "(spawn 1)")
+}
+
+func TestConcurrency_ChannelsConnectTasks(t *testing.T) {
+	src := `
+	(defun produce (ch n)
+	  (if (= n 0)
+	    (close ch)
+	    (do-send ch n)))
+	(defun do-send (ch n)
+	  (let (ignored (send ch n))
+	    (produce ch (- n 1))))
+	(defun consume (ch acc)
+	  (let (val (recv ch))
+	    (if (= val nil) acc (consume ch (+ acc val)))))
+	(defvar ch (chan))
+	(spawn produce ch 100)
+	(consume ch 0)`
+
+	expectResult(t, src, "5050")
+}
+
+func TestConcurrency_ManyTasks(t *testing.T) {
+	src := `
+	(defvar ch (chan 10))
+	(defvar tasks (map (lambda (n) (spawn (lambda () (send ch (* n n))))) (range 1 51)))
+	(reduce + 0 (map (lambda (task) (recv ch)) tasks))`
+
+	expectResult(t, src, "42925")
+}
+
+func TestConcurrency_Select(t *testing.T) {
+	expectResult(t, "(defvar ch (chan 1)) (send ch 1) (select (recv ch x (+ x 1)) (default 0))", "2")
+	expectResult(t, "(defvar ch (chan)) (select (recv ch x x) (default \"empty\"))", "empty")
+	expectResult(t, "(defvar ch (chan 1)) (select (send ch 5 (recv ch)) (default 0))", "5")
+	expectResult(t, "(defvar ch (chan)) (close ch) (select (recv ch x x))", "<nil>")
+	expectResult(t, "(defvar a (chan)) (defvar b (chan)) (spawn send b 2) (select (recv a x x) (recv b y (* y 10)))", "20")
+
+	err := expectError(t, "(select (recv (spawn / 1 0) x x))")
+	if !strings.Contains(err.Error(), "Division by zero") {
+		t.Fatalf("Expected division by zero error, got %v", err)
+	}
+
+	expectError(t, "(select (recv 1 x x))")
+	expectError(t, "(defvar ch (chan 1)) (close ch) (select (send ch 1 1))")
+}
+
+func TestConcurrency_ClosedChannels(t *testing.T) {
+	for src, msg := range map[string]string{
+		"(defvar ch (chan 1)) (close ch) (send ch 1)": "Cannot send on a closed channel",
+		"(defvar ch (chan 1)) (close ch) (close ch)":  "Cannot close a closed channel",
+		"(send 1 1)": "<send> expects a channel",
+		"(defvar ch (chan 1)) (send ch 1) (close ch) 1": "",
+	} {
+		if msg == "" {
+			expectResult(t, src, "1")
+			continue
+		}
+
+		err := expectError(t, src)
+		if !strings.Contains(err.Error(), msg) {
+			t.Fatalf("Expected error containing '%s' for %s, got %v", msg, src, err)
+		}
+	}
+}
+
+func TestConcurrency_InterpretFromMultipleGoroutines(t *testing.T) {
+	interpreter := NewInterpreter()
+
+	if _, err := interpreter.Interpret(parse(t, "(defvar squares (map (lambda (x) (* x x)) (range)))")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 20)
+	errs := make([]error, 20)
+
+	for n := range results {
+		expressions := parse(t, fmt.Sprintf("(defvar v%d %d) (let (x v%d) (+ x (nth squares 50)))", n, n, n))
+
+		wg.Add(1)
+
+		go func(n int) {
+			defer wg.Done()
+			results[n], errs[n] = interpreter.Interpret(expressions)
+		}(n)
+	}
+
+	wg.Wait()
+
+	for n, ret := range results {
+		if errs[n] != nil {
+			t.Fatalf("Expected no error, got %v", errs[n])
+		}
+
+		if ret != float64(2500+n) {
+			t.Fatalf("Expected %d, got %v", 2500+n, ret)
+		}
+	}
+}
+
+// parse scans and parses a piece of source code.
+func parse(t *testing.T, src string) []Expression {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tokens, ok := NewScanner(src, &buf).Scan()
+	if !ok {
+		t.Fatalf("Expected source to scan, got %s", buf.String())
+	}
+
+	expressions, err := NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Expected source to parse, got %v", err)
+	}
+
+	return expressions
+}
@@ -0,0 +1,251 @@
+package minimalisp
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Step tells a paused script how to continue.
+type Step int
+
+const (
+	// Continue runs the script until the next breakpoint.
+	Continue Step = iota
+	// StepIn pauses at the next call or definition.
+	StepIn
+	// StepOver pauses at the next call or definition after the current
+	// expression was evaluated.
+	StepOver
+	// StepOut pauses after the current function returned.
+	StepOut
+)
+
+// Frame is a function call on the call stack of a script.
+type Frame struct {
+	Name string
+	// Line is the line of the call.
+	Line int
+}
+
+// position is where a script paused or passed last.
+type position struct {
+	line  int
+	depth int
+}
+
+// Debugger pauses scripts at breakpoints and steps through calls and
+// definitions. Scripts pause before their first expression.
+type Debugger struct {
+	mutex     sync.Mutex
+	lines     map[int]bool
+	functions map[string]bool
+
+	// pausing makes tasks pause one after another.
+	pausing sync.Mutex
+	onPause func(p *Pause) Step
+
+	step Step
+	// nest and depth are the nesting and call depth of the expression the
+	// script paused at, which StepOver and StepOut compare against.
+	nest  int
+	depth int
+	// entered is set when a function with a breakpoint was called.
+	entered bool
+	// last is the position of the previous expression, so that a line
+	// breakpoint pauses only once for all calls on the line.
+	last position
+}
+
+// NewDebugger creates a debugger. onPause is called whenever a script
+// pauses and returns how the script continues.
+func NewDebugger(onPause func(p *Pause) Step) *Debugger {
+	return &Debugger{
+		lines:     make(map[int]bool),
+		functions: make(map[string]bool),
+		onPause:   onPause,
+		step:      StepIn,
+	}
+}
+
+// WithDebugger makes the interpreter pause scripts for a debugger. Debugged
+// scripts always use the tree-walking backend without optimizations.
+func WithDebugger(d *Debugger) Option {
+	return func(i *Interpreter) {
+		i.debugger = d
+	}
+}
+
+// BreakAtLine pauses the script before the first call or definition on a
+// line. Lines of loaded files and modules are not considered.
+func (d *Debugger) BreakAtLine(line int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.lines[line] = true
+}
+
+// BreakAtFunction pauses the script at the first call or definition in the
+// body of a function whenever the function is called by name.
+func (d *Debugger) BreakAtFunction(name string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.functions[name] = true
+}
+
+// ClearBreakpoints removes all breakpoints.
+func (d *Debugger) ClearBreakpoints() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.lines = make(map[int]bool)
+	d.functions = make(map[string]bool)
+}
+
+// shouldPause returns whether the task pauses before an expression on line.
+func (d *Debugger) shouldPause(i *Interpreter, line int) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	pos := position{line, len(i.frames)}
+	breakpoint := d.lines[line] && len(i.files) <= 1 && pos != d.last
+	d.last = pos
+
+	switch {
+	case d.entered, breakpoint, d.step == StepIn:
+		return true
+	case d.step == StepOver:
+		return i.nest <= d.nest
+	case d.step == StepOut:
+		return len(i.frames) < d.depth
+	}
+
+	return false
+}
+
+// pause calls onPause and remembers how the script continues.
+func (d *Debugger) pause(i *Interpreter, expr Expression, line int) {
+	d.pausing.Lock()
+	defer d.pausing.Unlock()
+
+	step := d.onPause(&Pause{Line: line, Expression: expr, interpreter: i, env: i.current})
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.step = step
+	d.nest = i.nest
+	d.depth = len(i.frames)
+	d.entered = false
+}
+
+// enter tells the debugger that a function was called.
+func (d *Debugger) enter(name string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.functions[name] {
+		d.entered = true
+	}
+}
+
+// leave tells the debugger that a function returned. A function without
+// calls or definitions in its body returns before the script could pause.
+func (d *Debugger) leave() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.entered = false
+}
+
+// Pause is a script paused by a debugger.
+type Pause struct {
+	// Line is the line of the expression which is evaluated next.
+	Line int
+	// Expression is the call or definition which is evaluated next.
+	Expression Expression
+
+	interpreter *Interpreter
+	env         *Environment
+}
+
+// Stack returns the function calls leading to the paused expression with
+// the innermost call first.
+func (p *Pause) Stack() []Frame {
+	frames := make([]Frame, len(p.interpreter.frames))
+
+	for n, frame := range p.interpreter.frames {
+		frames[len(frames)-1-n] = frame
+	}
+
+	return frames
+}
+
+// Environment returns the environment the paused expression is evaluated
+// in.
+func (p *Pause) Environment() *Environment {
+	return p.env
+}
+
+// Eval evaluates source code in the environment of the paused expression,
+// so that local variables can be inspected. The debugger does not pause
+// while the code is evaluated.
+func (p *Pause) Eval(src string) (interface{}, error) {
+	var errors bytes.Buffer
+
+	tokens, ok := NewScanner(src, &errors).Scan()
+	if !ok {
+		return nil, fmt.Errorf("%s", strings.TrimSpace(errors.String()))
+	}
+
+	expressions, err := NewParser(tokens).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	task := p.interpreter.fork()
+	task.debugger = nil
+	// The paused task may be running the thunk of a lazy sequence.
+	task.realizing = p.interpreter.realizing
+
+	var ret interface{}
+
+	// The expressions are not resolved, so variables are looked up by
+	// name in the paused environment.
+	for _, expr := range expressions {
+		if ret, err = task.execute(expr, p.env); err != nil {
+			return nil, err
+		}
+	}
+
+	return ret, nil
+}
+
+// trace pauses before an expression on line if the debugger asks for it.
+// The returned function must be called once the expression was evaluated.
+func (i *Interpreter) trace(expr Expression, line int) func() {
+	if i.debugger.shouldPause(i, line) {
+		i.debugger.pause(i, expr, line)
+	}
+
+	i.nest++
+
+	return func() {
+		i.nest--
+	}
+}
+
+// callTraced calls a function on the call stack of the debugger.
+func (i *Interpreter) callTraced(name Token, fun Function, arguments []interface{}) (interface{}, error) {
+	i.frames = append(i.frames, Frame{name.Lexeme, name.Line})
+	i.debugger.enter(name.Lexeme)
+
+	defer func() {
+		i.frames = i.frames[:len(i.frames)-1]
+		i.debugger.leave()
+	}()
+
+	return callFunction(name.Line, i, fun, arguments)
+}
@@ -0,0 +1,117 @@
+package minimalisp_test
+
+import (
+	"fmt"
+	"testing"
+
+	. "bakku.dev/minimalisp"
+)
+
+const debugScript = `(defun square (x)
+  (* x x))
+
+(defun sum-squares (a b)
+  (+ (square a)
+     (square b)))
+
+(defvar result (sum-squares 2 3))
+(+ result 1)`
+
+// debug runs the debug script and calls onPause for every pause. The
+// lines the script paused at are returned.
+func debug(t *testing.T, setup func(d *Debugger), onPause func(p *Pause) Step) []int {
+	t.Helper()
+
+	var lines []int
+
+	debugger := NewDebugger(func(p *Pause) Step {
+		lines = append(lines, p.Line)
+		return onPause(p)
+	})
+
+	if setup != nil {
+		setup(debugger)
+	}
+
+	interpreter := NewInterpreter(WithDebugger(debugger), WithBackend(Bytecode))
+	if _, err := interpreter.Interpret(parse(t, debugScript)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	return lines
+}
+
+func TestDebugger_StepsIntoEveryCall(t *testing.T) {
+	lines := debug(t, nil, func(p *Pause) Step {
+		return StepIn
+	})
+
+	if fmt.Sprint(lines) != "[1 4 8 8 5 5 2 6 2 9]" {
+		t.Fatalf("Unexpected steps %v", lines)
+	}
+}
+
+func TestDebugger_StepsOverAndOut(t *testing.T) {
+	steps := []Step{StepIn, StepIn, StepIn, StepIn, StepIn, StepOver, StepOut}
+
+	lines := debug(t, nil, func(p *Pause) Step {
+		if len(steps) == 0 {
+			return Continue
+		}
+
+		step := steps[0]
+		steps = steps[1:]
+		return step
+	})
+
+	// The script steps into sum-squares, over square a to square b and
+	// out of sum-squares to the last line.
+	if fmt.Sprint(lines) != "[1 4 8 8 5 5 6 9]" {
+		t.Fatalf("Unexpected steps %v", lines)
+	}
+}
+
+func TestDebugger_PausesAtBreakpoints(t *testing.T) {
+	var stack []Frame
+	var x interface{}
+
+	lines := debug(t, func(d *Debugger) {
+		d.BreakAtLine(9)
+		d.BreakAtFunction("square")
+	}, func(p *Pause) Step {
+		if p.Line == 2 && stack == nil {
+			stack = p.Stack()
+
+			var err error
+			if x, err = p.Eval("(+ x 1)"); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		}
+
+		return Continue
+	})
+
+	if fmt.Sprint(lines) != "[1 2 2 9]" {
+		t.Fatalf("Unexpected pauses %v", lines)
+	}
+
+	if fmt.Sprint(stack) != "[{square 5} {sum-squares 8}]" {
+		t.Fatalf("Unexpected stack %v", stack)
+	}
+
+	if x != 3.0 {
+		t.Fatalf("Expected x to be 2 in the paused environment, got %v", x)
+	}
+}
+
+func TestDebugger_PausesOncePerLine(t *testing.T) {
+	lines := debug(t, func(d *Debugger) {
+		d.BreakAtLine(8)
+	}, func(p *Pause) Step {
+		return Continue
+	})
+
+	if fmt.Sprint(lines) != "[1 8]" {
+		t.Fatalf("Unexpected pauses %v", lines)
+	}
+}
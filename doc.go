@@ -0,0 +1,124 @@
+package minimalisp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Documentation describes how to use a function or variable.
+type Documentation struct {
+	Signature   string
+	Description string
+}
+
+// Documented is implemented by functions which document themselves.
+type Documented interface {
+	Doc() Documentation
+}
+
+// Doc returns the documentation of a variable.
+func (i *Interpreter) Doc(name string) (Documentation, error) {
+	token := Token{Identifier, name, -1, nil}
+
+	val, err := i.lookupVar(token)
+	if err != nil {
+		return Documentation{}, fmt.Errorf("'%s' is not defined", name)
+	}
+
+	// Aliases of builtins share their value, so the name is checked first.
+	if builtin, err := i.builtins.Get(token); err == nil && builtin == val {
+		if doc, ok := builtinDocs[name]; ok {
+			return doc, nil
+		}
+	}
+
+	doc, ok := i.documentation(val)
+	if !ok {
+		return Documentation{}, fmt.Errorf("'%s' has no documentation", name)
+	}
+
+	return doc, nil
+}
+
+// documentation returns the documentation of self-documenting functions and
+// builtins.
+func (i *Interpreter) documentation(val interface{}) (Documentation, bool) {
+	if documented, ok := val.(Documented); ok {
+		return documented.Doc(), true
+	}
+
+	for _, name := range i.builtins.Names() {
+		builtin, _ := i.builtins.Get(Token{Identifier, name, -1, nil})
+		if builtin != val {
+			continue
+		}
+
+		doc, ok := builtinDocs[name]
+		return doc, ok
+	}
+
+	return Documentation{}, false
+}
+
+// Doc returns the signature and the docstring of the function.
+func (f *MinimalispFunction) Doc() Documentation {
+	return Documentation{signature(f.name, f.params, f.body), f.doc}
+}
+
+// signature returns how a function is called. Destructured parameters are
+// generated names bound by the body, so their patterns are shown instead.
+func signature(name string, params []Token, body Expression) string {
+	patterns := make(map[string]string)
+
+	for {
+		destructure, ok := body.(*DestructureExpr)
+		if !ok {
+			break
+		}
+
+		switch v := destructure.Value.(type) {
+		case *VarExpr:
+			patterns[v.Name.Lexeme] = destructure.Pattern.String()
+		case *LocalExpr:
+			patterns[v.Name.Lexeme] = destructure.Pattern.String()
+		}
+
+		body = destructure.Body
+	}
+
+	parts := []string{name}
+
+	for _, param := range params {
+		if pattern, ok := patterns[param.Lexeme]; ok {
+			parts = append(parts, pattern)
+		} else {
+			parts = append(parts, param.Lexeme)
+		}
+	}
+
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+// DocBuiltin returns the docstring of a function or nil if it has none.
+// Usage:
+// (doc map)
+type DocBuiltin struct{}
+
+// Arity returns 1.
+func (f *DocBuiltin) Arity() int {
+	return 1
+}
+
+// Call implements doc.
+func (f *DocBuiltin) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	doc, ok := i.documentation(arguments[0])
+	if !ok || doc.Description == "" {
+		return nil, nil
+	}
+
+	return doc.Description, nil
+}
+
+func (f *DocBuiltin) String() string {
+	return "<doc>"
+}
@@ -0,0 +1,73 @@
+package minimalisp_test
+
+import (
+	"testing"
+
+	. "bakku.dev/minimalisp"
+)
+
+func TestDoc_Docstrings(t *testing.T) {
+	expectResult(t, "(defun greet (name) \"Greets someone.\" (+ \"Hi \" name)) (doc greet)", "Greets someone.")
+	expectResult(t, "(defun greet (name) \"Greets someone.\" name) (greet \"Bob\")", "Bob")
+	expectResult(t, "(defun greet (name) name) (doc greet)", "<nil>")
+	expectResult(t, "(defun greet () \"Hi\") (greet)", "Hi")
+	expectResult(t, "(defun greet () \"Hi\") (doc greet)", "<nil>")
+	expectResult(t, "(doc (lambda (x) x))", "<nil>")
+	expectResult(t, "(doc map)", "Applies fn to the elements of the lists and returns the results. Stops at the end of the shortest list.")
+	expectResult(t, "(doc (partial + 1))", "<nil>")
+	expectResult(t, "(doc 1)", "<nil>")
+}
+
+func TestDoc_InterpreterDoc(t *testing.T) {
+	interpreter := NewInterpreter()
+
+	src := `
+	(defun sum-pair ((a b) &rest others) "Adds a pair." (+ a b))
+	(defstruct point x y)
+	(defvar x 1)`
+
+	if _, err := interpreter.Interpret(parse(t, src)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for name, expected := range map[string]Documentation{
+		"sum-pair":     {"(sum-pair (a b) &rest others)", "Adds a pair."},
+		"make-point":   {"(make-point x y)", "Creates a point record."},
+		"point-with-y": {"(point-with-y point value)", "Returns a copy of a point record with a different y."},
+		"reduce":       {"(reduce fn [initial] list)", "Combines the elements of a list into a single value using fn."},
+		"*out*":        {"*out*", "The output stream println writes to. Defaults to standard output."},
+	} {
+		doc, err := interpreter.Doc(name)
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", name, err)
+		}
+
+		if doc != expected {
+			t.Fatalf("Expected %v for %s, got %v", expected, name, doc)
+		}
+	}
+
+	if _, err := interpreter.Doc("x"); err == nil {
+		t.Fatalf("Expected an error for undocumented variable")
+	}
+
+	if _, err := interpreter.Doc("unknown"); err == nil {
+		t.Fatalf("Expected an error for undefined variable")
+	}
+}
+
+func TestDoc_AllBuiltinsAreDocumented(t *testing.T) {
+	interpreter := NewInterpreter()
+
+	for _, name := range interpreter.Builtins().Names() {
+		doc, err := interpreter.Doc(name)
+		if err != nil {
+			t.Errorf("Expected documentation of %s, got %v", name, err)
+			continue
+		}
+
+		if doc.Signature == "" || doc.Description == "" {
+			t.Errorf("Expected a signature and a description of %s, got %#v", name, doc)
+		}
+	}
+}
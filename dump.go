@@ -0,0 +1,89 @@
+package minimalisp
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var tokenType = reflect.TypeOf(Token{})
+
+// DumpExpression returns the tree of an expression with one node or field
+// per line, which shows how the parser understood the source code. Tokens
+// are shown as their lexemes.
+func DumpExpression(expr Expression) string {
+	var b strings.Builder
+	dump(&b, reflect.ValueOf(expr), 0)
+	return b.String()
+}
+
+func dump(b *strings.Builder, v reflect.Value, indent int) {
+	prefix := strings.Repeat("  ", indent)
+
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			b.WriteString(prefix + "nil\n")
+			return
+		}
+
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Type() == tokenType:
+		b.WriteString(prefix + v.Interface().(Token).Lexeme + "\n")
+	case v.Kind() == reflect.Struct:
+		b.WriteString(prefix + v.Type().Name() + "\n")
+
+		for n := 0; n < v.NumField(); n++ {
+			field := v.Type().Field(n)
+			if field.PkgPath != "" {
+				continue
+			}
+
+			if inline, ok := dumpInline(v.Field(n)); ok {
+				b.WriteString(fmt.Sprintf("%s  %s: %s\n", prefix, field.Name, inline))
+				continue
+			}
+
+			b.WriteString(fmt.Sprintf("%s  %s:\n", prefix, field.Name))
+			dump(b, v.Field(n), indent+2)
+		}
+	case v.Kind() == reflect.Slice:
+		for n := 0; n < v.Len(); n++ {
+			dump(b, v.Index(n), indent)
+		}
+	default:
+		b.WriteString(fmt.Sprintf("%s%#v\n", prefix, v.Interface()))
+	}
+}
+
+// dumpInline returns values which fit behind the name of their field.
+func dumpInline(v reflect.Value) (string, bool) {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "nil", true
+		}
+
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Type() == tokenType:
+		return v.Interface().(Token).Lexeme, true
+	case v.Kind() == reflect.Slice && v.Len() == 0:
+		return "[]", true
+	case v.Kind() == reflect.Slice && v.Type().Elem() == tokenType:
+		var lexemes []string
+
+		for n := 0; n < v.Len(); n++ {
+			lexemes = append(lexemes, v.Index(n).Interface().(Token).Lexeme)
+		}
+
+		return "[" + strings.Join(lexemes, " ") + "]", true
+	case v.Kind() == reflect.Struct || v.Kind() == reflect.Slice:
+		return "", false
+	}
+
+	return fmt.Sprintf("%#v", v.Interface()), true
+}
@@ -0,0 +1,37 @@
+package minimalisp_test
+
+import (
+	"testing"
+
+	. "bakku.dev/minimalisp"
+)
+
+func TestDumpExpression_ShouldShowTheTree(t *testing.T) {
+	expressions := parse(t, "(defun add (a b) (+ a 1))")
+
+	expected := `DefunExpr
+  Name: add
+  Params: [a b]
+  Doc: ""
+  Body:
+    FuncCallExpr
+      Name: +
+      Arguments:
+        VarExpr
+          Name: a
+        LiteralExpr
+          Value: 1
+`
+
+	if dump := DumpExpression(expressions[0]); dump != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s", expected, dump)
+	}
+}
+
+func TestDumpExpression_ShouldShowMissingExpressions(t *testing.T) {
+	dump := DumpExpression(&LetExpr{})
+
+	if dump != "LetExpr\n  Names: []\n  Values: []\n  Body: nil\n" {
+		t.Fatalf("Unexpected dump\n%s", dump)
+	}
+}
@@ -0,0 +1,125 @@
+package minimalisp
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// DynamicVar is a variable defined with defparameter. Its value can be
+// rebound for the dynamic extent of a parameterize expression.
+type DynamicVar struct {
+	Name string
+	root interface{}
+}
+
+// NewDynamicVar is a factory function to create a new dynamic variable.
+func NewDynamicVar(name string, root interface{}) *DynamicVar {
+	return &DynamicVar{name, root}
+}
+
+func (v *DynamicVar) String() string {
+	return "<dynamic " + v.Name + ">"
+}
+
+// dynamicBinding is an immutable stack of parameterized values. Tasks
+// inherit the bindings which were active when they were started.
+type dynamicBinding struct {
+	variable *DynamicVar
+	value    interface{}
+	next     *dynamicBinding
+}
+
+// dynamicValue returns the innermost binding of a dynamic variable.
+func (i *Interpreter) dynamicValue(v *DynamicVar) interface{} {
+	for b := i.dynamic; b != nil; b = b.next {
+		if b.variable == v {
+			return b.value
+		}
+	}
+
+	return v.root
+}
+
+// builtinValue returns the current value of a dynamic variable of the
+// standard library, regardless of whether user code shadows it.
+func (i *Interpreter) builtinValue(name string) interface{} {
+	v, _ := i.builtins.Get(Token{Identifier, name, -1, nil})
+	return i.dynamicValue(v.(*DynamicVar))
+}
+
+func (i *Interpreter) visitDefparameterExpr(defparameterExpr *DefparameterExpr) (interface{}, error) {
+	if i.debugger != nil {
+		defer i.trace(defparameterExpr, defparameterExpr.Name.Line)()
+	}
+
+	val, err := defparameterExpr.Initializer.Accept(i)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := i.define(defparameterExpr.Name, NewDynamicVar(defparameterExpr.Name.Lexeme, val)); err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
+func (i *Interpreter) visitParameterizeExpr(parameterizeExpr *ParameterizeExpr) (interface{}, error) {
+	bindings := i.dynamic
+
+	for n, name := range parameterizeExpr.Names {
+		raw, err := i.lookupVar(name)
+		if err != nil {
+			return nil, err
+		}
+
+		variable, ok := raw.(*DynamicVar)
+		if !ok {
+			return nil, &executionError{name.Line, fmt.Sprintf("'%s' is not a dynamic variable", name.Lexeme)}
+		}
+
+		val, err := parameterizeExpr.Values[n].Accept(i)
+		if err != nil {
+			return nil, err
+		}
+
+		bindings = &dynamicBinding{variable, val, bindings}
+	}
+
+	prev := i.dynamic
+	i.dynamic = bindings
+	defer func() { i.dynamic = prev }()
+
+	return parameterizeExpr.Body.Accept(i)
+}
+
+// Output is a stream which println writes to. Writes of different tasks
+// are serialized.
+type Output struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+// NewOutput is a factory function to create a new output stream.
+func NewOutput(w io.Writer) *Output {
+	return &Output{writer: w}
+}
+
+func (o *Output) Write(p []byte) (int, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return o.writer.Write(p)
+}
+
+func (o *Output) String() string {
+	return "<output>"
+}
+
+// WithOutput sets the stream println writes to unless *out* is rebound.
+func WithOutput(w io.Writer) Option {
+	return func(i *Interpreter) {
+		_ = i.builtins.Assign(Token{Identifier, "*out*", -1, nil}, NewDynamicVar("*out*", NewOutput(w)))
+	}
+}
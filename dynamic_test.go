@@ -0,0 +1,88 @@
+package minimalisp_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "bakku.dev/minimalisp"
+)
+
+func TestDynamic_Parameterize(t *testing.T) {
+	expectResult(t, "(defparameter *x* 1) *x*", "1")
+	expectResult(t, "(defparameter *x* 1) (parameterize ((*x* 2)) *x*)", "2")
+	expectResult(t, "(defparameter *x* 1) (parameterize ((*x* 2)) *x*) *x*", "1")
+	expectResult(t, "(defparameter *x* 1) (defparameter *y* 1) (parameterize ((*x* 2) (*y* *x*)) '(*x* *y*))", "(2 1)")
+	expectResult(t, "(defparameter *x* 1) (parameterize ((*x* 2)) (parameterize ((*x* 3)) *x*))", "3")
+	expectResult(t, "(defparameter *f* +) (parameterize ((*f* *)) (*f* 2 3))", "6")
+}
+
+func TestDynamic_UsesDynamicScope(t *testing.T) {
+	src := `
+	(defparameter *x* 1)
+	(defun get-x () *x*)
+	(defvar in-scope (parameterize ((*x* 2)) (get-x)))
+	'(in-scope (get-x))`
+
+	expectResult(t, src, "(2 1)")
+	expectResult(t, "(defparameter *x* 1) (defun get-x () *x*) (parameterize ((*x* 2)) (recv (spawn get-x)))", "2")
+	expectResult(t, "(defparameter *x* 1) (defun get-x () *x*) (parameterize ((*x* 2)) (deref (future get-x)))", "2")
+}
+
+func TestDynamic_RestoresBindingsOnError(t *testing.T) {
+	interpreter := NewInterpreter()
+
+	if _, err := interpreter.Interpret(parse(t, "(defparameter *x* 1) (defun get-x () *x*)")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := interpreter.Interpret(parse(t, "(parameterize ((*x* 2)) (/ 1 0))")); err == nil {
+		t.Fatalf("Expected an error")
+	}
+
+	ret, err := interpreter.Interpret(parse(t, "(get-x)"))
+	if err != nil || ret != 1.0 {
+		t.Fatalf("Expected 1, got %v and %v", ret, err)
+	}
+
+	expectError(t, "(defparameter *x* 1) (parameterize ((*x* (/ 1 0))) *x*)")
+}
+
+func TestDynamic_RejectsLexicalVariables(t *testing.T) {
+	err := expectError(t, "(defvar y 1) (parameterize ((y 2)) y)")
+	if !strings.Contains(err.Error(), "'y' is not a dynamic variable") {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expectError(t, "(parameterize ((*unknown* 2)) 1)")
+}
+
+func TestDynamic_PrintlnWritesToOut(t *testing.T) {
+	var out, other bytes.Buffer
+
+	interpreter := NewInterpreter(WithOutput(&out))
+	if err := interpreter.Define("other", NewOutput(&other)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	src := `
+	(println "a" 1)
+	(parameterize ((*out* other)) (println "b"))
+	(println "c")`
+
+	if _, err := interpreter.Interpret(parse(t, src)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if out.String() != "a 1\nc\n" {
+		t.Fatalf("Unexpected output %q", out.String())
+	}
+
+	if other.String() != "b\n" {
+		t.Fatalf("Unexpected output %q", other.String())
+	}
+
+	if _, err := interpreter.Interpret(parse(t, "(parameterize ((*out* 1)) (println 1))")); err == nil {
+		t.Fatalf("Expected an error")
+	}
+}
@@ -1,42 +1,108 @@
 package minimalisp
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
 
-// Environment acts as a map to store and lookup values.
+// Environment acts as a map to store and lookup values. Values are stored
+// in slots in the order they were defined, so that the resolver can compute
+// where local variables are found. It can be used by multiple goroutines at
+// once.
 type Environment struct {
-	values    map[string]interface{}
+	mutex     sync.RWMutex
+	values    map[string]int
+	slots     []interface{}
 	enclosing *Environment
 }
 
 // NewEnvironment is a factory function to create a new environment.
 func NewEnvironment() *Environment {
 	return &Environment{
-		values: make(map[string]interface{}),
+		values: make(map[string]int),
 	}
 }
 
 // NewEnvironmentWithEnclosing create a new environment with a parent environment.
 func NewEnvironmentWithEnclosing(enclosing *Environment) *Environment {
 	return &Environment{
-		values:    make(map[string]interface{}),
+		values:    make(map[string]int),
 		enclosing: enclosing,
 	}
 }
 
 // Define adds a new variable to the environment.
 func (e *Environment) Define(token Token, value interface{}) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
 	_, ok := e.values[token.Lexeme]
 	if ok {
 		return &executionError{token.Line, fmt.Sprintf("Variable '%s' already defined", token.Lexeme)}
 	}
 
-	e.values[token.Lexeme] = value
+	e.values[token.Lexeme] = len(e.slots)
+	e.slots = append(e.slots, value)
 	return nil
 }
 
+// Assign changes the value of an existing variable.
+func (e *Environment) Assign(token Token, value interface{}) error {
+	e.mutex.Lock()
+
+	if slot, ok := e.values[token.Lexeme]; ok {
+		e.slots[slot] = value
+		e.mutex.Unlock()
+		return nil
+	}
+
+	e.mutex.Unlock()
+
+	if e.enclosing != nil {
+		return e.enclosing.Assign(token, value)
+	}
+
+	return &executionError{token.Line, fmt.Sprintf("Undefined variable '%s'.", token.Lexeme)}
+}
+
+// has returns whether a variable is defined in the environment itself,
+// ignoring enclosing environments.
+func (e *Environment) has(name string) bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	_, ok := e.values[name]
+	return ok
+}
+
+// Names returns the sorted names of all variables defined in the environment
+// itself, ignoring enclosing environments.
+func (e *Environment) Names() []string {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	var names []string
+
+	for name := range e.values {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
 // Get returns a variable from the environment.
 func (e *Environment) Get(token Token) (interface{}, error) {
-	val, ok := e.values[token.Lexeme]
+	e.mutex.RLock()
+	slot, ok := e.values[token.Lexeme]
+	var val interface{}
+	if ok {
+		val = e.slots[slot]
+	}
+	e.mutex.RUnlock()
+
 	if !ok {
 		if e.enclosing != nil {
 			return e.enclosing.Get(token)
@@ -47,3 +113,18 @@ func (e *Environment) Get(token Token) (interface{}, error) {
 
 	return val, nil
 }
+
+// getAt returns the value of a slot in the environment depth levels up the
+// chain of enclosing environments.
+func (e *Environment) getAt(depth, slot int) interface{} {
+	env := e
+
+	for ; depth > 0; depth-- {
+		env = env.enclosing
+	}
+
+	env.mutex.RLock()
+	defer env.mutex.RUnlock()
+
+	return env.slots[slot]
+}
@@ -10,3 +10,35 @@ type executionError struct {
 func (e *executionError) Error() string {
 	return fmt.Sprintf("[line %d] %s", e.line, e.msg)
 }
+
+// StaticError is returned when code is rejected before it runs, for example
+// because of a duplicate binding or a use before a definition.
+type StaticError struct {
+	Line int
+	Msg  string
+}
+
+func (e *StaticError) Error() string {
+	return fmt.Sprintf("[line %d] %s", e.Line, e.Msg)
+}
+
+// IncompleteInputError is returned when the source code ends in the middle
+// of an expression or a string. Appending more source code can complete it.
+type IncompleteInputError struct {
+	Line int
+	Msg  string
+}
+
+func (e *IncompleteInputError) Error() string {
+	return fmt.Sprintf("[line %d] %s", e.Line, e.Msg)
+}
+
+// ExitError is returned when a script calls exit. Programs embedding the
+// interpreter decide whether to end the process with the code.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
@@ -0,0 +1,19 @@
+package minimalisp_test
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestExamples_Run(t *testing.T) {
+	examples, err := filepath.Glob("examples/*.mlisp")
+	if err != nil || len(examples) == 0 {
+		t.Fatalf("Expected examples, got %v", err)
+	}
+
+	for _, example := range examples {
+		if _, err := interpretScript(t, example); err != nil {
+			t.Fatalf("Expected %s to run, got %v", example, err)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package minimalisp
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// formatWidth is the width the formatter tries to keep lines within.
+const formatWidth = 80
+
+// bodyForms maps special forms to the amount of their arguments which stay
+// on the line of the form. The remaining arguments are the body, which is
+// indented by two spaces.
+var bodyForms = map[string]int{
+	"defun":        2,
+	"lambda":       1,
+	"let":          1,
+	"let*":         1,
+	"letrec":       1,
+	"if":           1,
+	"match":        1,
+	"select":       0,
+	"parameterize": 1,
+	"defvar":       1,
+	"defparameter": 1,
+	"module":       1,
+}
+
+// bindingForms are the forms whose first argument is a flat list of names
+// and values, which is broken into one pair per line.
+var bindingForms = map[string]bool{
+	"let":    true,
+	"let*":   true,
+	"letrec": true,
+}
+
+// formatNode is an atom, a comment or a list of the source code.
+type formatNode struct {
+	token Token
+	// quotes is the amount of quotes in front of the node, which are all
+	// kept even if the parser rejects nested quotes.
+	quotes   int
+	list     bool
+	children []*formatNode
+	// line and endLine are the lines the node starts and ends on.
+	line    int
+	endLine int
+}
+
+func (n *formatNode) isComment() bool {
+	return !n.list && n.token.TokenType == Semicolon
+}
+
+// Format formats Minimalisp source code. Comments and single blank lines
+// are kept. Lists which fit into a line and were written on a single line
+// stay on one line. Otherwise special forms like defun, let, if and lambda
+// indent their body by two spaces and the arguments of function calls are
+// aligned with the first one.
+func Format(src string) (string, error) {
+	var errors bytes.Buffer
+
+	tokens, ok := NewScanner(src, &errors).ScanWithComments()
+	if !ok {
+		return "", fmt.Errorf("%s", strings.TrimSpace(errors.String()))
+	}
+
+	nodes, rest, err := formatNodes(tokens)
+	if err != nil {
+		return "", err
+	}
+
+	if rest[0].TokenType != EOF {
+		return "", &executionError{rest[0].Line, "Unexpected ')'"}
+	}
+
+	f := &formatter{}
+	f.sequence(nodes, 0)
+
+	if f.out.Len() == 0 {
+		return "", nil
+	}
+
+	return f.out.String() + "\n", nil
+}
+
+// formatNodes reads nodes until a closing paren or the end of the tokens.
+func formatNodes(tokens []Token) ([]*formatNode, []Token, error) {
+	var nodes []*formatNode
+
+	for tokens[0].TokenType != EOF && tokens[0].TokenType != RightParen {
+		node, rest, err := readFormatNode(tokens)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		nodes = append(nodes, node)
+		tokens = rest
+	}
+
+	return nodes, tokens, nil
+}
+
+// readFormatNode reads a single node.
+func readFormatNode(tokens []Token) (*formatNode, []Token, error) {
+	token := tokens[0]
+
+	switch token.TokenType {
+	case Quote:
+		next := tokens[1].TokenType
+		if next == EOF || next == RightParen || next == Semicolon {
+			return nil, nil, &executionError{token.Line, "Expected an expression after quote"}
+		}
+
+		node, rest, err := readFormatNode(tokens[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		node.quotes++
+		return node, rest, nil
+	case LeftParen:
+		children, rest, err := formatNodes(tokens[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if rest[0].TokenType != RightParen {
+			return nil, nil, &executionError{token.Line, "Unbalanced parentheses"}
+		}
+
+		return &formatNode{token: token, list: true, children: children, line: token.Line, endLine: rest[0].Line}, rest[1:], nil
+	}
+
+	endLine := token.Line + strings.Count(token.Lexeme, "\n")
+	return &formatNode{token: token, line: token.Line, endLine: endLine}, tokens[1:], nil
+}
+
+// formatter writes formatted nodes and keeps track of the current column.
+type formatter struct {
+	out    bytes.Buffer
+	column int
+}
+
+func (f *formatter) write(s string) {
+	f.out.WriteString(s)
+
+	if n := strings.LastIndex(s, "\n"); n >= 0 {
+		f.column = len(s) - n - 1
+	} else {
+		f.column += len(s)
+	}
+}
+
+func (f *formatter) newline(indent int) {
+	f.write("\n" + strings.Repeat(" ", indent))
+}
+
+// sequence writes nodes below each other at an indentation. Trailing
+// comments stay on the line of the node before them.
+func (f *formatter) sequence(nodes []*formatNode, indent int) {
+	for n, node := range nodes {
+		if n > 0 {
+			prev := nodes[n-1]
+
+			if node.isComment() && node.line == prev.endLine {
+				f.write(" ")
+				f.node(node)
+				continue
+			}
+
+			if node.line-prev.endLine > 1 {
+				f.write("\n")
+			}
+
+			f.newline(indent)
+		}
+
+		f.node(node)
+	}
+}
+
+func (f *formatter) node(node *formatNode) {
+	f.write(strings.Repeat("'", node.quotes))
+
+	if !node.list {
+		f.write(strings.TrimRight(node.token.Lexeme, " \t\r"))
+		return
+	}
+
+	if flat, ok := flatten(node); ok && node.line == node.endLine && f.column+len(flat) <= formatWidth {
+		f.write(flat)
+		return
+	}
+
+	f.list(node)
+}
+
+// flatten returns a node on a single line without its quote. ok is false if
+// the node contains comments or multi-line strings.
+func flatten(node *formatNode) (string, bool) {
+	if node.isComment() || strings.Contains(node.token.Lexeme, "\n") {
+		return "", false
+	}
+
+	if !node.list {
+		return node.token.Lexeme, true
+	}
+
+	var parts []string
+
+	for _, child := range node.children {
+		flat, ok := flatten(child)
+		if !ok {
+			return "", false
+		}
+
+		flat = strings.Repeat("'", child.quotes) + flat
+
+		parts = append(parts, flat)
+	}
+
+	return "(" + strings.Join(parts, " ") + ")", true
+}
+
+// list writes a list over multiple lines.
+func (f *formatter) list(node *formatNode) {
+	open := f.column
+	f.write("(")
+
+	children := node.children
+	if len(children) == 0 {
+		f.write(")")
+		return
+	}
+
+	head := children[0]
+	indent := open + 1
+	inline := 0
+
+	if node.quotes == 0 && !head.list && !head.isComment() {
+		if args, ok := bodyForms[head.token.Lexeme]; ok {
+			indent = open + 2
+			inline = args
+		} else if len(children) > 1 && !children[1].isComment() {
+			indent = open + len(head.token.Lexeme) + 2
+			inline = 1
+		}
+	}
+
+	f.node(head)
+
+	rest := children[1:]
+	for n := 0; n < inline && len(rest) > 0 && !rest[0].isComment(); n++ {
+		f.write(" ")
+
+		if n == 0 && bindingForms[head.token.Lexeme] && rest[0].list && rest[0].quotes == 0 {
+			f.bindings(rest[0])
+		} else {
+			f.node(rest[0])
+		}
+
+		rest = rest[1:]
+	}
+
+	if len(rest) > 0 {
+		prev := children[len(children)-len(rest)-1]
+
+		if rest[0].isComment() && rest[0].line == prev.endLine {
+			f.write(" ")
+		} else {
+			if rest[0].line-prev.endLine > 1 {
+				f.write("\n")
+			}
+
+			f.newline(indent)
+		}
+
+		f.sequence(rest, indent)
+	}
+
+	if children[len(children)-1].isComment() {
+		f.newline(indent)
+	}
+
+	f.write(")")
+}
+
+// bindings writes the bindings of a let. Lists which do not fit into a line
+// get one name and value per line.
+func (f *formatter) bindings(node *formatNode) {
+	if flat, ok := flatten(node); ok && node.line == node.endLine && f.column+len(flat) <= formatWidth {
+		f.write(flat)
+		return
+	}
+
+	indent := f.column + 1
+	f.write("(")
+
+	var pairs [][]*formatNode
+
+	for n := 0; n < len(node.children); n++ {
+		child := node.children[n]
+
+		if !child.isComment() && n+1 < len(node.children) && !node.children[n+1].isComment() {
+			pairs = append(pairs, node.children[n:n+2])
+			n++
+			continue
+		}
+
+		pairs = append(pairs, node.children[n:n+1])
+	}
+
+	var prev *formatNode
+
+	for _, pair := range pairs {
+		if prev != nil {
+			if pair[0].isComment() && pair[0].line == prev.endLine {
+				f.write(" ")
+			} else {
+				if pair[0].line-prev.endLine > 1 {
+					f.write("\n")
+				}
+
+				f.newline(indent)
+			}
+		}
+
+		f.node(pair[0])
+
+		if len(pair) == 2 {
+			f.write(" ")
+			f.node(pair[1])
+		}
+
+		prev = pair[len(pair)-1]
+	}
+
+	if prev != nil && prev.isComment() {
+		f.newline(indent)
+	}
+
+	f.write(")")
+}
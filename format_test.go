@@ -0,0 +1,110 @@
+package minimalisp_test
+
+import (
+	"testing"
+
+	. "bakku.dev/minimalisp"
+)
+
+func expectFormatted(t *testing.T, src, expected string) {
+	t.Helper()
+
+	formatted, err := Format(src)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if formatted != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s", expected, formatted)
+	}
+
+	again, err := Format(formatted)
+	if err != nil || again != formatted {
+		t.Fatalf("Expected formatting to be idempotent, got\n%s", again)
+	}
+}
+
+func TestFormat_KeepsShortListsOnOneLine(t *testing.T) {
+	expectFormatted(t, "(+   1\t2)", "(+ 1 2)\n")
+	expectFormatted(t, "'( 1 2 '(3))", "'(1 2 '(3))\n")
+	expectFormatted(t, "''( 1 2)", "''(1 2)\n")
+	expectFormatted(t, "(f ' 'x)", "(f ''x)\n")
+	expectFormatted(t, "", "")
+}
+
+func TestFormat_IndentsSpecialForms(t *testing.T) {
+	expectFormatted(t, `(defun add (a b)
+(+ a b))`, `(defun add (a b)
+  (+ a b))
+`)
+
+	expectFormatted(t, `(if (> a 1)
+"big"
+     "small")`, `(if (> a 1)
+  "big"
+  "small")
+`)
+
+	expectFormatted(t, `(let (a 1
+b 2)
+(+ a b))`, `(let (a 1
+      b 2)
+  (+ a b))
+`)
+
+	expectFormatted(t, `(map (lambda (x)
+(* x x)) '(1 2))`, `(map (lambda (x)
+       (* x x))
+     '(1 2))
+`)
+}
+
+func TestFormat_PreservesComments(t *testing.T) {
+	expectFormatted(t, `; header
+
+(defun f (x) ; trailing
+  ; body
+  (g x))`, `; header
+
+(defun f (x) ; trailing
+  ; body
+  (g x))
+`)
+
+	expectFormatted(t, "#!/usr/bin/env mlisp\n(f  1)", "#!/usr/bin/env mlisp\n(f 1)\n")
+
+	expectFormatted(t, `(list 1 ; one
+)`, `(list 1 ; one
+      )
+`)
+}
+
+func TestFormat_ReportsErrors(t *testing.T) {
+	sources := []string{"(+ 1", "(+ 1))", "'", "\"open"}
+
+	for _, src := range sources {
+		if _, err := Format(src); err == nil {
+			t.Fatalf("Expected an error for %s", src)
+		}
+	}
+}
+
+func TestFormat_DoesNotChangeSemantics(t *testing.T) {
+	src := `(defun fact (n) (if (<= n 1) 1 (* n (fact (- n 1)))))
+(let (a 5
+b 2) ; comment
+(+ (fact a)
+b))`
+
+	formatted, err := Format(src)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected, _ := NewInterpreter().Interpret(parse(t, src))
+	ret, err := NewInterpreter().Interpret(parse(t, formatted))
+
+	if err != nil || ret != expected {
+		t.Fatalf("Expected %v, got %v and %v", expected, ret, err)
+	}
+}
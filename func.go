@@ -1,5 +1,7 @@
 package minimalisp
 
+import "fmt"
+
 // infiniteArity is a constant which allows a function to have an infinite arity.
 const infiniteArity = -1
 
@@ -9,37 +11,80 @@ type Function interface {
 	Call(line int, interpreter *Interpreter, args []interface{}) (interface{}, error)
 }
 
+// callFunction checks the amount of arguments and calls the function.
+func callFunction(line int, interpreter *Interpreter, fun Function, args []interface{}) (interface{}, error) {
+	if len(args) != fun.Arity() && fun.Arity() != infiniteArity {
+		return nil, &executionError{line, fmt.Sprintf("Expected %d arguments but got %d", fun.Arity(), len(args))}
+	}
+
+	return fun.Call(line, interpreter, args)
+}
+
 // MinimalispFunction is the standard function which is used in the minimalisp interpreter.
 type MinimalispFunction struct {
 	name    string
 	params  []Token
 	body    Expression
 	closure *Environment
+	doc     string
 }
 
 // NewMinimalispFunction is a factory function to create a new function.
-func NewMinimalispFunction(name string, params []Token, body Expression, closure *Environment) Function {
-	return &MinimalispFunction{name, params, body, closure}
+func NewMinimalispFunction(name string, params []Token, body Expression, closure *Environment, doc string) Function {
+	return &MinimalispFunction{name, params, body, closure, doc}
 }
 
 // Arity returns the amount of params which are expected for a function call.
+// Functions with a rest parameter accept any amount of arguments.
 func (f *MinimalispFunction) Arity() int {
+	if f.restIndex() >= 0 {
+		return infiniteArity
+	}
+
 	return len(f.params)
 }
 
 // Call calls the function.
 func (f *MinimalispFunction) Call(line int, interpreter *Interpreter, args []interface{}) (interface{}, error) {
 	env := NewEnvironmentWithEnclosing(f.closure)
+	params := f.params
+	rest := f.restIndex()
 
-	for i, p := range f.params {
+	if rest >= 0 {
+		if len(args) < rest {
+			return nil, &executionError{line, fmt.Sprintf("Expected at least %d arguments but got %d", rest, len(args))}
+		}
+
+		params = params[:rest]
+	}
+
+	// Parameters are defined in order as the resolver assigns their slots.
+	for i, p := range params {
 		if err := env.Define(p, args[i]); err != nil {
 			return nil, err
 		}
 	}
 
+	if rest >= 0 {
+		if err := env.Define(f.params[rest+1], NewArrayList(args[rest:])); err != nil {
+			return nil, err
+		}
+	}
+
 	return interpreter.execute(f.body, env)
 }
 
+// restIndex returns the position of '&rest' in the params or -1.
+func (f *MinimalispFunction) restIndex() int {
+	for i, p := range f.params {
+		if p.TokenType == AmpRest {
+			return i
+		}
+	}
+
+	return -1
+}
+
 func (f *MinimalispFunction) String() string {
 	return "<" + f.name + ">"
 }
@@ -0,0 +1,328 @@
+package minimalisp
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Apply calls a function with the elements of a list as arguments.
+// Usage:
+// (apply + '(1 2 3)) => 6
+// (apply + 1 2 '(3 4)) => 10
+type Apply struct{}
+
+// Arity returns infiniteArity as arguments may precede the list.
+func (f *Apply) Arity() int {
+	return infiniteArity
+}
+
+// Call implements apply.
+func (f *Apply) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	if len(arguments) < 2 {
+		return nil, &executionError{line, "<apply> expects a function and a list"}
+	}
+
+	fun, ok := arguments[0].(Function)
+	if !ok {
+		return nil, &executionError{line, "<apply> expects a function as first parameter"}
+	}
+
+	list, ok := arguments[len(arguments)-1].(List)
+	if !ok {
+		return nil, &executionError{line, "<apply> expects a list as last parameter"}
+	}
+
+	elements, err := toSlice(i, list)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []interface{}
+	args = append(args, arguments[1:len(arguments)-1]...)
+	args = append(args, elements...)
+
+	return callFunction(line, i, fun, args)
+}
+
+func (f *Apply) String() string {
+	return "<apply>"
+}
+
+// Partial returns a function with some of its arguments already given.
+// Usage:
+// (defvar add-ten (partial + 10))
+// (add-ten 5) => 15
+type Partial struct{}
+
+// Arity returns infiniteArity as any amount of arguments can be bound.
+func (f *Partial) Arity() int {
+	return infiniteArity
+}
+
+// Call implements partial.
+func (f *Partial) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	if len(arguments) < 1 {
+		return nil, &executionError{line, "<partial> expects a function as first parameter"}
+	}
+
+	fun, ok := arguments[0].(Function)
+	if !ok {
+		return nil, &executionError{line, "<partial> expects a function as first parameter"}
+	}
+
+	if fun.Arity() != infiniteArity && len(arguments)-1 > fun.Arity() {
+		return nil, &executionError{line, fmt.Sprintf("<partial> got %d arguments for a function which accepts %d", len(arguments)-1, fun.Arity())}
+	}
+
+	return &PartialFunction{fun, arguments[1:]}, nil
+}
+
+func (f *Partial) String() string {
+	return "<partial>"
+}
+
+// PartialFunction is a function with some of its arguments already given.
+type PartialFunction struct {
+	fun  Function
+	args []interface{}
+}
+
+// Arity returns the amount of arguments which are still missing.
+func (f *PartialFunction) Arity() int {
+	if f.fun.Arity() == infiniteArity {
+		return infiniteArity
+	}
+
+	return f.fun.Arity() - len(f.args)
+}
+
+// Call calls the wrapped function with the bound and the given arguments.
+func (f *PartialFunction) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	var args []interface{}
+	args = append(args, f.args...)
+	args = append(args, arguments...)
+
+	return callFunction(line, i, f.fun, args)
+}
+
+func (f *PartialFunction) String() string {
+	return "<partial>"
+}
+
+// Comp composes functions from right to left.
+// Usage:
+// (defvar h (comp f g))
+// (h x) is the same as (f (g x))
+type Comp struct{}
+
+// Arity returns infiniteArity for comp.
+func (f *Comp) Arity() int {
+	return infiniteArity
+}
+
+// Call implements comp.
+func (f *Comp) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	if len(arguments) < 1 {
+		return nil, &executionError{line, "<comp> requires at least one argument"}
+	}
+
+	var funs []Function
+
+	for n, arg := range arguments {
+		fun, ok := arg.(Function)
+		if !ok {
+			return nil, &executionError{line, "<comp> is only defined for functions"}
+		}
+
+		if n != len(arguments)-1 && fun.Arity() != 1 && fun.Arity() != infiniteArity {
+			return nil, &executionError{line, "<comp> expects all except the last function to accept one argument"}
+		}
+
+		funs = append(funs, fun)
+	}
+
+	return &ComposedFunction{funs}, nil
+}
+
+func (f *Comp) String() string {
+	return "<comp>"
+}
+
+// ComposedFunction is the result of composing functions.
+type ComposedFunction struct {
+	funs []Function
+}
+
+// Arity returns the arity of the innermost function.
+func (f *ComposedFunction) Arity() int {
+	return f.funs[len(f.funs)-1].Arity()
+}
+
+// Call calls the functions from right to left passing along the result.
+func (f *ComposedFunction) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	ret, err := callFunction(line, i, f.funs[len(f.funs)-1], arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	for n := len(f.funs) - 2; n >= 0; n-- {
+		if ret, err = callFunction(line, i, f.funs[n], []interface{}{ret}); err != nil {
+			return nil, err
+		}
+	}
+
+	return ret, nil
+}
+
+func (f *ComposedFunction) String() string {
+	return "<comp>"
+}
+
+// Identity returns its argument.
+type Identity struct{}
+
+// Arity returns 1.
+func (f *Identity) Arity() int {
+	return 1
+}
+
+// Call implements identity.
+func (f *Identity) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	return arguments[0], nil
+}
+
+func (f *Identity) String() string {
+	return "<identity>"
+}
+
+// Constantly returns a function which always returns the same value.
+// Usage:
+// (map (constantly 1) '(a b)) => (1 1)
+type Constantly struct{}
+
+// Arity returns 1.
+func (f *Constantly) Arity() int {
+	return 1
+}
+
+// Call implements constantly.
+func (f *Constantly) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	return &ConstantFunction{arguments[0]}, nil
+}
+
+func (f *Constantly) String() string {
+	return "<constantly>"
+}
+
+// ConstantFunction ignores its arguments and always returns the same value.
+type ConstantFunction struct {
+	value interface{}
+}
+
+// Arity returns infiniteArity for a constant function.
+func (f *ConstantFunction) Arity() int {
+	return infiniteArity
+}
+
+// Call returns the constant value.
+func (f *ConstantFunction) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	return f.value, nil
+}
+
+func (f *ConstantFunction) String() string {
+	return "<constantly>"
+}
+
+// Memoize returns a function which caches the results of another function.
+type Memoize struct{}
+
+// Arity returns 1.
+func (f *Memoize) Arity() int {
+	return 1
+}
+
+// Call implements memoize.
+func (f *Memoize) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	fun, ok := arguments[0].(Function)
+	if !ok {
+		return nil, &executionError{line, "<memoize> expects a function as first parameter"}
+	}
+
+	return &MemoizedFunction{fun: fun, cache: make(map[string]interface{})}, nil
+}
+
+func (f *Memoize) String() string {
+	return "<memoize>"
+}
+
+// MemoizedFunction caches the results of a function by its arguments.
+type MemoizedFunction struct {
+	fun   Function
+	mutex sync.Mutex
+	cache map[string]interface{}
+}
+
+// Arity returns the arity of the memoized function.
+func (f *MemoizedFunction) Arity() int {
+	return f.fun.Arity()
+}
+
+// Call returns the cached result or calls the memoized function.
+func (f *MemoizedFunction) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	key := memoKey(arguments)
+
+	f.mutex.Lock()
+	ret, ok := f.cache[key]
+	f.mutex.Unlock()
+
+	if ok {
+		return ret, nil
+	}
+
+	ret, err := callFunction(line, i, f.fun, arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mutex.Lock()
+	f.cache[key] = ret
+	f.mutex.Unlock()
+
+	return ret, nil
+}
+
+func (f *MemoizedFunction) String() string {
+	return "<memoize>"
+}
+
+// memoKey builds a cache key which distinguishes values by type and content.
+// Numbers, strings, booleans and nil are compared by value, array lists by
+// their elements and all other values like functions, atoms and records by
+// identity.
+func memoKey(values []interface{}) string {
+	var b strings.Builder
+
+	for _, val := range values {
+		switch v := val.(type) {
+		case *ArrayList:
+			b.WriteString("(" + memoKey(v.elements) + ")")
+		case nil, float64, bool:
+			b.WriteString(fmt.Sprintf("%T:%v", v, v))
+		case string:
+			b.WriteString(fmt.Sprintf("%T:%q", v, v))
+		default:
+			switch reflect.ValueOf(v).Kind() {
+			case reflect.Ptr, reflect.Chan, reflect.Func, reflect.Map, reflect.Slice, reflect.UnsafePointer:
+				b.WriteString(fmt.Sprintf("%T:%p", v, v))
+			default:
+				b.WriteString(fmt.Sprintf("%T:%#v", v, v))
+			}
+		}
+
+		b.WriteString(" ")
+	}
+
+	return b.String()
+}
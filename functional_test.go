@@ -0,0 +1,74 @@
+package minimalisp_test
+
+import (
+	"testing"
+
+	. "bakku.dev/minimalisp"
+)
+
+func TestFunctional_Apply(t *testing.T) {
+	expectResult(t, "(apply + '(1 2 3))", "6")
+	expectResult(t, "(apply + 1 2 '(3 4))", "10")
+	expectResult(t, "(apply (lambda (a b) (- a b)) '(5 3))", "2")
+	expectError(t, "(apply (lambda (a b) (- a b)) '(5))")
+}
+
+func TestFunctional_Partial(t *testing.T) {
+	expectResult(t, "(defvar add-ten (partial + 10)) (add-ten 5)", "15")
+	expectResult(t, "(defvar sub (partial (lambda (a b) (- a b)) 10)) (map sub '(1 2))", "(9 8)")
+	expectError(t, "(partial (lambda (a) a) 1 2)")
+}
+
+func TestFunctional_Comp(t *testing.T) {
+	expectResult(t, "(defvar f (comp (lambda (x) (* x 2)) +)) (f 1 2 3)", "12")
+	expectResult(t, "(map (comp first rest) '('(1 2) '(3 4)))", "(2 4)")
+}
+
+func TestFunctional_IdentityAndConstantly(t *testing.T) {
+	expectResult(t, "(filter identity '(1 false nil 2))", "(1 2)")
+	expectResult(t, "(map (constantly 1) '(4 5 6))", "(1 1 1)")
+}
+
+// countingFunction counts how often it has been called.
+type countingFunction struct {
+	calls int
+}
+
+func (f *countingFunction) Arity() int {
+	return 1
+}
+
+func (f *countingFunction) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	f.calls++
+	return arguments[0], nil
+}
+
+func TestFunctional_Memoize(t *testing.T) {
+	counter := &countingFunction{}
+	interpreter := NewInterpreter()
+
+	memoized, err := (&Memoize{}).Call(1, interpreter, []interface{}{counter})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, arg := range []interface{}{1.0, 1.0, "1", 2.0} {
+		ret, err := memoized.(Function).Call(1, interpreter, []interface{}{arg})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if ret != arg {
+			t.Fatalf("Expected '%v' as result, got '%v'", arg, ret)
+		}
+	}
+
+	if counter.calls != 3 {
+		t.Fatalf("Expected 3 calls, got %d", counter.calls)
+	}
+}
+
+func TestFunctional_MemoizeKeysReferencesByIdentity(t *testing.T) {
+	expectResult(t, "(let (m (memoize (lambda (f) (f 1)))) '((m (lambda (x) (+ x 1))) (m (lambda (x) (* x 100)))))", "(2 100)")
+	expectResult(t, "(let (m (memoize (lambda (a) (deref a)))) '((m (atom 1)) (m (atom 2))))", "(1 2)")
+}
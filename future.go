@@ -0,0 +1,278 @@
+package minimalisp
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// pending is a value which may not be available yet.
+type pending interface {
+	// done is closed once the value is available.
+	done() <-chan struct{}
+	result() (interface{}, error)
+}
+
+// Future is the result of a function which is called on another goroutine.
+type Future struct {
+	finished chan struct{}
+	value    interface{}
+	err      error
+}
+
+func (f *Future) done() <-chan struct{} {
+	return f.finished
+}
+
+func (f *Future) result() (interface{}, error) {
+	return f.value, f.err
+}
+
+func (f *Future) String() string {
+	select {
+	case <-f.finished:
+		return fmt.Sprintf("<future %v>", f.value)
+	default:
+		return "<future pending>"
+	}
+}
+
+// Promise is a value which is delivered once by some task.
+type Promise struct {
+	once      sync.Once
+	delivered chan struct{}
+	value     interface{}
+}
+
+// NewPromise is a factory function to create a new promise.
+func NewPromise() *Promise {
+	return &Promise{delivered: make(chan struct{})}
+}
+
+// Deliver sets the value of the promise. It returns false if the promise
+// was already delivered, in which case the value is ignored.
+func (p *Promise) Deliver(value interface{}) bool {
+	delivered := false
+
+	p.once.Do(func() {
+		p.value = value
+		close(p.delivered)
+		delivered = true
+	})
+
+	return delivered
+}
+
+func (p *Promise) done() <-chan struct{} {
+	return p.delivered
+}
+
+func (p *Promise) result() (interface{}, error) {
+	return p.value, nil
+}
+
+func (p *Promise) String() string {
+	select {
+	case <-p.delivered:
+		return fmt.Sprintf("<promise %v>", p.value)
+	default:
+		return "<promise pending>"
+	}
+}
+
+// FutureBuiltin calls a function with the given arguments on a new
+// goroutine. Its result is obtained with deref.
+// Usage:
+// (deref (future + 1 2)) => 3
+type FutureBuiltin struct{}
+
+// Arity returns infiniteArity as the arguments of the function are passed along.
+func (f *FutureBuiltin) Arity() int {
+	return infiniteArity
+}
+
+// Call implements future.
+func (f *FutureBuiltin) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	if len(arguments) == 0 {
+		return nil, &executionError{line, "<future> expects a function and its arguments"}
+	}
+
+	fun, err := expectFunction(line, "future", arguments[0], len(arguments)-1)
+	if err != nil {
+		return nil, err
+	}
+
+	future := &Future{finished: make(chan struct{})}
+	task := i.fork()
+
+	go func() {
+		future.value, future.err = fun.Call(line, task, arguments[1:])
+		close(future.finished)
+	}()
+
+	return future, nil
+}
+
+func (f *FutureBuiltin) String() string {
+	return "<future>"
+}
+
+// PromiseBuiltin creates a promise.
+// Usage:
+// (promise)
+type PromiseBuiltin struct{}
+
+// Arity returns 0.
+func (f *PromiseBuiltin) Arity() int {
+	return 0
+}
+
+// Call implements promise.
+func (f *PromiseBuiltin) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	return NewPromise(), nil
+}
+
+func (f *PromiseBuiltin) String() string {
+	return "<promise>"
+}
+
+// Deliver sets the value of a promise. It returns false if the promise was
+// already delivered.
+// Usage:
+// (deliver p 1) => true
+type Deliver struct{}
+
+// Arity returns 2.
+func (f *Deliver) Arity() int {
+	return 2
+}
+
+// Call implements deliver.
+func (f *Deliver) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	p, ok := arguments[0].(*Promise)
+	if !ok {
+		return nil, &executionError{line, "<deliver> expects a promise as first parameter"}
+	}
+
+	return p.Deliver(arguments[1]), nil
+}
+
+func (f *Deliver) String() string {
+	return "<deliver>"
+}
+
+// PMap applies a function to the elements of a list in parallel and returns
+// the results in order. At most GOMAXPROCS elements are processed at the
+// same time. If the function fails, the error of the first failing element
+// is returned.
+// Usage:
+// (pmap (lambda (x) (* x x)) '(1 2 3)) => (1 4 9)
+type PMap struct{}
+
+// Arity returns 2.
+func (f *PMap) Arity() int {
+	return 2
+}
+
+// Call implements pmap.
+func (f *PMap) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	fun, err := expectFunction(line, "pmap", arguments[0], 1)
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := arguments[1].(List)
+	if !ok {
+		return nil, &executionError{line, "<pmap> expects a list as second parameter"}
+	}
+
+	elements, err := toSlice(i, list)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := parallelMap(line, i, fun, elements, runtime.GOMAXPROCS(0))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewArrayList(results), nil
+}
+
+func (f *PMap) String() string {
+	return "<pmap>"
+}
+
+// parallelMap calls a function for every element using a pool of workers.
+// No new elements are started once an element failed.
+func parallelMap(line int, i *Interpreter, fun Function, elements []interface{}, workers int) ([]interface{}, error) {
+	results := make([]interface{}, len(elements))
+	errs := make([]error, len(elements))
+
+	indices := make(chan int)
+	failed := make(chan struct{})
+	var failOnce sync.Once
+	var wg sync.WaitGroup
+
+	if workers > len(elements) {
+		workers = len(elements)
+	}
+
+	for w := 0; w < workers; w++ {
+		task := i.fork()
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for n := range indices {
+				if results[n], errs[n] = fun.Call(line, task, []interface{}{elements[n]}); errs[n] != nil {
+					failOnce.Do(func() { close(failed) })
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for n := range elements {
+		select {
+		case indices <- n:
+		case <-failed:
+			break dispatch
+		}
+	}
+
+	close(indices)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		switch err.(type) {
+		case *executionError, *ExitError:
+		default:
+			err = &executionError{line, fmt.Sprintf("<pmap> failed: %v", err)}
+		}
+
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// waitFor returns the value of a pending value or the timeout value if it
+// is not available within timeout.
+func waitFor(p pending, timeout time.Duration, timeoutValue interface{}) (interface{}, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-p.done():
+		return p.result()
+	case <-timer.C:
+		return timeoutValue, nil
+	}
+}
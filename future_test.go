@@ -0,0 +1,147 @@
+package minimalisp_test
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "bakku.dev/minimalisp"
+)
+
+func TestFuture_Deref(t *testing.T) {
+	expectResult(t, "(deref (future + 1 2))", "3")
+	expectResult(t, "(deref (future (lambda () (reduce + 0 (range 10)))))", "45")
+	expectResult(t, "(defvar f (future + 1 2)) (deref f) (deref f)", "3")
+
+	err := expectError(t, "(deref (future / 1 0))")
+	if !strings.Contains(err.Error(), "Division by zero") {
+		t.Fatalf("Expected division by zero error, got %v", err)
+	}
+
+	expectError(t, "(future (lambda (x) x))")
+	expectError(t, "(deref (future + 1 2) 10)")
+}
+
+func TestFuture_DerefWithTimeout(t *testing.T) {
+	expectResult(t, "(deref (future + 1 2) 1000 \"timeout\")", "3")
+	expectResult(t, "(defvar ch (chan)) (defvar r (deref (future recv ch) 10 \"timeout\")) (close ch) r", "timeout")
+	expectResult(t, "(deref (promise) 0 nil)", "<nil>")
+
+	expectError(t, "(deref (promise) \"soon\" nil)")
+}
+
+func TestFuture_Promises(t *testing.T) {
+	expectResult(t, "(defvar p (promise)) (spawn deliver p 42) (deref p)", "42")
+	expectResult(t, "(defvar p (promise)) (deliver p 1)", "true")
+	expectResult(t, "(defvar p (promise)) (deliver p 1) (deliver p 2)", "false")
+	expectResult(t, "(defvar p (promise)) (deliver p 1) (deliver p 2) (deref p)", "1")
+	expectResult(t, "(defvar p (promise)) (deliver p 1) p", "<promise 1>")
+
+	expectError(t, "(deliver 1 2)")
+}
+
+func TestFuture_PMapPreservesOrder(t *testing.T) {
+	expectResult(t, "(pmap (lambda (x) (* x x)) '(1 2 3 4 5))", "(1 4 9 16 25)")
+	expectResult(t, "(pmap (lambda (x) (* x 2)) (take 100 (range)))", doubled(100))
+	expectResult(t, "(pmap (lambda (x) x) '())", "()")
+
+	expectError(t, "(pmap (lambda (x y) x) '(1 2))")
+	expectError(t, "(pmap (lambda (x) x) 1)")
+}
+
+func TestFuture_PMapPropagatesFirstError(t *testing.T) {
+	interpreter := NewInterpreter()
+	if err := interpreter.Define("check", &failingFunction{from: 5}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err := interpreter.Interpret(parse(t, "(pmap check (range 20))"))
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+
+	if err.Error() != "[line 1] <pmap> failed: element 5 is too large" {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	err = expectError(t, "(pmap (lambda (x) (/ 1 (- x 3))) (range 10))")
+	if !strings.Contains(err.Error(), "Division by zero") {
+		t.Fatalf("Expected division by zero error, got %v", err)
+	}
+}
+
+func TestFuture_PMapBoundsWorkers(t *testing.T) {
+	fun := &concurrencyCounter{}
+
+	interpreter := NewInterpreter()
+	if err := interpreter.Define("work", fun); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := interpreter.Interpret(parse(t, "(pmap work (range 50))")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if fun.max > runtime.GOMAXPROCS(0) {
+		t.Fatalf("Expected at most %d concurrent calls, got %d", runtime.GOMAXPROCS(0), fun.max)
+	}
+}
+
+// doubled prints the list of the first n even numbers.
+func doubled(n int) string {
+	var ret []string
+
+	for k := 0; k < n; k++ {
+		ret = append(ret, fmt.Sprint(2*k))
+	}
+
+	return "(" + strings.Join(ret, " ") + ")"
+}
+
+// failingFunction fails for all numbers starting with from.
+type failingFunction struct {
+	from float64
+}
+
+func (f *failingFunction) Arity() int {
+	return 1
+}
+
+func (f *failingFunction) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	if n := arguments[0].(float64); n >= f.from {
+		return nil, fmt.Errorf("element %v is too large", n)
+	}
+
+	return arguments[0], nil
+}
+
+// concurrencyCounter records how many calls run at the same time.
+type concurrencyCounter struct {
+	mutex   sync.Mutex
+	current int
+	max     int
+}
+
+func (f *concurrencyCounter) Arity() int {
+	return 1
+}
+
+func (f *concurrencyCounter) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	f.mutex.Lock()
+	f.current++
+	if f.current > f.max {
+		f.max = f.current
+	}
+	f.mutex.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	f.mutex.Lock()
+	f.current--
+	f.mutex.Unlock()
+
+	return arguments[0], nil
+}
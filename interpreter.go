@@ -2,33 +2,187 @@ package minimalisp
 
 import (
 	"fmt"
+	"io"
+	"sort"
+	"strings"
 )
 
 // Interpreter is an implementation of the AST visitor.
 type Interpreter struct {
-	globals *Environment
-	current *Environment
+	// builtins contains the standard library and encloses all global
+	// environments so that user code can shadow builtins.
+	builtins *Environment
+	globals  *Environment
+	current  *Environment
+
+	// warnings receives warnings about suspicious code if it is set.
+	warnings io.Writer
+	// redefine allows global definitions to replace existing ones.
+	redefine bool
+	// onRedefine is called with the name of a redefined global.
+	onRedefine func(name string)
+
+	// modules caches loaded modules and is shared by all tasks.
+	modules *moduleCache
+	// module is the module which is currently loaded by this task.
+	module *Module
+	// files is the stack of files which are currently interpreted.
+	files []string
+	// dynamic holds the values of parameterized dynamic variables.
+	dynamic *dynamicBinding
+
+	// backend executes the code.
+	backend Backend
+	// optimize enables the optimizer.
+	optimize bool
+	// stack holds the values of the virtual machine of this task.
+	stack []interface{}
+
+	// debugger pauses the script if it is set.
+	debugger *Debugger
+	// frames is the call stack of this task while debugging.
+	frames []Frame
+	// nest counts the calls and definitions which are being evaluated
+	// while debugging.
+	nest int
+
+	// realizing is the lazy sequence whose thunk this task runs, so that
+	// the thunk asking for the sequence again is detected.
+	realizing *realization
+}
+
+// Option configures an Interpreter.
+type Option func(*Interpreter)
+
+// WithShadowWarnings makes the interpreter warn whenever a global
+// definition shadows a builtin.
+func WithShadowWarnings(w io.Writer) Option {
+	return func(i *Interpreter) {
+		i.warnings = w
+	}
+}
+
+// WithRedefinition allows redefining global variables and functions, which
+// is useful for interactive sessions. Closures referring to a global see
+// its new value. notify is called for every redefinition and may be nil.
+func WithRedefinition(notify func(name string)) Option {
+	return func(i *Interpreter) {
+		i.redefine = true
+		i.onRedefine = notify
+	}
 }
 
 // NewInterpreter is a factory function to create a new Interpreter.
-func NewInterpreter() *Interpreter {
-	global := NewEnvironment()
-	setupStdlib(global)
-	return &Interpreter{global, global}
+func NewInterpreter(options ...Option) *Interpreter {
+	builtins := newStdlibEnvironment()
+	global := NewEnvironmentWithEnclosing(builtins)
+
+	i := &Interpreter{
+		builtins: builtins,
+		globals:  global,
+		current:  global,
+		modules:  &moduleCache{modules: make(map[string]*Module)},
+	}
+
+	for _, option := range options {
+		option(i)
+	}
+
+	if i.debugger != nil {
+		i.backend = TreeWalker
+		i.optimize = false
+	}
+
+	return i
 }
 
-// Interpret takes a slice of expressions and interprets them.
+// Interpret takes a slice of expressions and interprets them. It is safe to
+// call Interpret from multiple goroutines at once.
 func (i *Interpreter) Interpret(expressions []Expression) (interface{}, error) {
-	var ret interface{}
-	var err error = nil
+	return i.fork().interpret(expressions)
+}
+
+// Define defines a global variable, which allows Go programs to pass values
+// like atoms or channels to scripts.
+func (i *Interpreter) Define(name string, value interface{}) error {
+	return i.globals.Define(Token{Identifier, name, -1, nil}, value)
+}
+
+// Globals returns the environment of the global variables defined by the
+// interpreted code and by Define.
+func (i *Interpreter) Globals() *Environment {
+	return i.globals
+}
+
+// Builtins returns the environment of the standard library, which encloses
+// the global environment.
+func (i *Interpreter) Builtins() *Environment {
+	return i.builtins
+}
+
+// Completions returns the sorted names of all global variables, builtins and
+// keywords which start with prefix, which is useful for tab completion.
+func (i *Interpreter) Completions(prefix string) []string {
+	seen := make(map[string]bool)
+	var completions []string
+
+	add := func(name string) {
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			seen[name] = true
+			completions = append(completions, name)
+		}
+	}
+
+	for env := i.globals; env != nil; env = env.enclosing {
+		for _, name := range env.Names() {
+			add(name)
+		}
+	}
 
-	for _, expr := range expressions {
-		if ret, err = expr.Accept(i); err != nil {
+	for keyword := range keywords {
+		add(keyword)
+	}
+
+	sort.Strings(completions)
+
+	return completions
+}
+
+func (i *Interpreter) interpret(expressions []Expression) (interface{}, error) {
+	expressions, err := i.prepare(expressions)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.evaluate(expressions)
+}
+
+// prepare resolves and optionally optimizes expressions before they are
+// evaluated. Errors are reported for the expressions as they were written,
+// even if the optimizer removes the code containing them.
+func (i *Interpreter) prepare(expressions []Expression) ([]Expression, error) {
+	if i.optimize {
+		if _, err := i.resolve(expressions); err != nil {
 			return nil, err
 		}
+
+		expressions = i.Optimize(expressions)
 	}
 
-	return ret, nil
+	return i.resolve(expressions)
+}
+
+// fork creates an interpreter for a new task. Tasks share the globals, the
+// builtins and loaded modules but have their own evaluation state, so that
+// they can run on different goroutines. An interpreter must only be used by
+// one goroutine at a time.
+func (i *Interpreter) fork() *Interpreter {
+	task := *i
+	task.files = append([]string(nil), i.files...)
+	task.stack = nil
+	task.frames = append([]Frame(nil), i.frames...)
+	task.realizing = nil
+	return &task
 }
 
 func (i *Interpreter) execute(expression Expression, env *Environment) (interface{}, error) {
@@ -44,12 +198,16 @@ func (i *Interpreter) visitLiteralExpr(literalExpr *LiteralExpr) (interface{}, e
 }
 
 func (i *Interpreter) visitDefvarExpr(defvarExpr *DefvarExpr) (interface{}, error) {
+	if i.debugger != nil {
+		defer i.trace(defvarExpr, defvarExpr.Name.Line)()
+	}
+
 	val, err := defvarExpr.Initializer.Accept(i)
 	if err != nil {
 		return nil, err
 	}
 
-	err = i.current.Define(defvarExpr.Name, val)
+	err = i.define(defvarExpr.Name, val)
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +216,7 @@ func (i *Interpreter) visitDefvarExpr(defvarExpr *DefvarExpr) (interface{}, erro
 }
 
 func (i *Interpreter) visitVarExpr(varExpr *VarExpr) (interface{}, error) {
-	return i.current.Get(varExpr.Name)
+	return i.lookup(varExpr.Name)
 }
 
 func (i *Interpreter) visitIfExpr(ifExpr *IfExpr) (interface{}, error) {
@@ -75,29 +233,55 @@ func (i *Interpreter) visitIfExpr(ifExpr *IfExpr) (interface{}, error) {
 }
 
 func (i *Interpreter) visitDefunExpr(defunExpr *DefunExpr) (interface{}, error) {
-	fun := NewMinimalispFunction(defunExpr.Name.Lexeme, defunExpr.Params, defunExpr.Body, i.current)
+	if i.debugger != nil {
+		defer i.trace(defunExpr, defunExpr.Name.Line)()
+	}
+
+	fun := NewMinimalispFunction(defunExpr.Name.Lexeme, defunExpr.Params, defunExpr.Body, i.current, defunExpr.Doc)
 
-	if err := i.current.Define(defunExpr.Name, fun); err != nil {
+	if err := i.define(defunExpr.Name, fun); err != nil {
 		return nil, err
 	}
 
 	return fun, nil
 }
 
+func (i *Interpreter) visitLocalExpr(localExpr *LocalExpr) (interface{}, error) {
+	return i.current.getAt(localExpr.Depth, localExpr.Slot), nil
+}
+
 func (i *Interpreter) visitFuncCallExpr(funcCallExpr *FuncCallExpr) (interface{}, error) {
-	fun, err := i.current.Get(funcCallExpr.Name)
+	if i.debugger != nil {
+		defer i.trace(funcCallExpr, funcCallExpr.Name.Line)()
+	}
+
+	fun, err := i.lookup(funcCallExpr.Name)
 	if err != nil {
 		return nil, err
 	}
 
+	return i.call(funcCallExpr.Name, fun, funcCallExpr.Arguments)
+}
+
+func (i *Interpreter) visitLocalCallExpr(localCallExpr *LocalCallExpr) (interface{}, error) {
+	if i.debugger != nil {
+		defer i.trace(localCallExpr, localCallExpr.Name.Line)()
+	}
+
+	fun := i.current.getAt(localCallExpr.Depth, localCallExpr.Slot)
+	return i.call(localCallExpr.Name, fun, localCallExpr.Arguments)
+}
+
+// call evaluates the arguments and calls the function bound to name.
+func (i *Interpreter) call(name Token, fun interface{}, args []Expression) (interface{}, error) {
 	callableFun, ok := fun.(Function)
 	if !ok {
-		return nil, &executionError{funcCallExpr.Name.Line, fmt.Sprintf("%s is not a function", funcCallExpr.Name.Lexeme)}
+		return nil, &executionError{name.Line, fmt.Sprintf("%s is not a function", name.Lexeme)}
 	}
 
 	var arguments []interface{}
 
-	for _, arg := range funcCallExpr.Arguments {
+	for _, arg := range args {
 		val, err := arg.Accept(i)
 		if err != nil {
 			return nil, err
@@ -106,11 +290,11 @@ func (i *Interpreter) visitFuncCallExpr(funcCallExpr *FuncCallExpr) (interface{}
 		arguments = append(arguments, val)
 	}
 
-	if len(arguments) != callableFun.Arity() && callableFun.Arity() != infiniteArity {
-		return nil, &executionError{funcCallExpr.Name.Line, fmt.Sprintf("Expected %d arguments but got %d", callableFun.Arity(), len(arguments))}
+	if i.debugger != nil {
+		return i.callTraced(name, callableFun, arguments)
 	}
 
-	return callableFun.Call(funcCallExpr.Name.Line, i, arguments)
+	return callFunction(name.Line, i, callableFun, arguments)
 }
 
 func (i *Interpreter) visitListExpr(listExpr *ListExpr) (interface{}, error) {
@@ -150,8 +334,92 @@ func (i *Interpreter) visitLetExpr(letExpr *LetExpr) (interface{}, error) {
 	return ret, nil
 }
 
+func (i *Interpreter) visitLetStarExpr(letStarExpr *LetStarExpr) (interface{}, error) {
+	letEnv := i.current
+
+	// Every binding has its own scope, so that a binding can shadow the
+	// previous ones.
+	for n, name := range letStarExpr.Names {
+		val, err := i.execute(letStarExpr.Values[n], letEnv)
+		if err != nil {
+			return nil, err
+		}
+
+		letEnv = NewEnvironmentWithEnclosing(letEnv)
+		if err = letEnv.Define(name, val); err != nil {
+			return nil, err
+		}
+	}
+
+	return i.execute(letStarExpr.Body, letEnv)
+}
+
+func (i *Interpreter) visitLetrecExpr(letrecExpr *LetrecExpr) (interface{}, error) {
+	letEnv := NewEnvironmentWithEnclosing(i.current)
+
+	for _, name := range letrecExpr.Names {
+		if err := letEnv.Define(name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	for n, name := range letrecExpr.Names {
+		val, err := i.execute(letrecExpr.Values[n], letEnv)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = letEnv.Assign(name, val); err != nil {
+			return nil, err
+		}
+	}
+
+	return i.execute(letrecExpr.Body, letEnv)
+}
+
 func (i *Interpreter) visitLambdaExpr(lambdaExpr *LambdaExpr) (interface{}, error) {
-	return NewMinimalispFunction("lambda", lambdaExpr.Params, lambdaExpr.Body, i.current), nil
+	return NewMinimalispFunction("lambda", lambdaExpr.Params, lambdaExpr.Body, i.current, ""), nil
+}
+
+func (i *Interpreter) visitDestructureExpr(destructureExpr *DestructureExpr) (interface{}, error) {
+	val, err := destructureExpr.Value.Accept(i)
+	if err != nil {
+		return nil, err
+	}
+
+	env := NewEnvironmentWithEnclosing(i.current)
+
+	if err := destructureExpr.Pattern.bind(i, destructureExpr.Pattern.line(), val, env); err != nil {
+		return nil, err
+	}
+
+	return i.execute(destructureExpr.Body, env)
+}
+
+// define defines a variable in the current environment. Globals are
+// replaced in redefinition mode and shadowed builtins are warned about.
+func (i *Interpreter) define(name Token, value interface{}) error {
+	if i.redefine && i.current == i.globals && i.current.has(name.Lexeme) {
+		if err := i.current.Assign(name, value); err != nil {
+			return err
+		}
+
+		if i.onRedefine != nil {
+			i.onRedefine(name.Lexeme)
+		}
+
+		return nil
+	}
+
+	if err := i.current.Define(name, value); err != nil {
+		return err
+	}
+
+	if i.warnings != nil && i.current == i.globals && i.builtins.has(name.Lexeme) {
+		_, _ = fmt.Fprintf(i.warnings, "[line %d] Warning: '%s' shadows a builtin\n", name.Line, name.Lexeme)
+	}
+
+	return nil
 }
 
 func isTruthy(val interface{}) bool {
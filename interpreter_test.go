@@ -1,6 +1,9 @@
 package minimalisp_test
 
 import (
+	"bytes"
+	"fmt"
+	"strings"
 	"testing"
 
 	. "bakku.dev/minimalisp"
@@ -12,15 +15,16 @@ func TestInterpret_ShouldCorrectlyInterpretCode1(t *testing.T) {
 		&VarExpr{Token{Identifier, "name", 2, nil}},
 	}
 
-	interpreter := NewInterpreter()
-	ret, err := interpreter.Interpret(expressions)
+	for name, interpreter := range interpreters() {
+		ret, err := interpreter.Interpret(expressions)
 
-	if err != nil {
-		t.Fatalf("Expected no error, got %v", err)
-	}
+		if err != nil {
+			t.Fatalf("Expected no error from the %s interpreter, got %v", name, err)
+		}
 
-	if ret != "Steven" {
-		t.Fatalf("Expected 'Steven' as result, got '%v'", ret)
+		if ret != "Steven" {
+			t.Fatalf("Expected 'Steven' as result, got '%v' from the %s interpreter", ret, name)
+		}
 	}
 }
 
@@ -33,15 +37,16 @@ func TestInterpret_ShouldCorrectlyInterpretCode2(t *testing.T) {
 		},
 	}
 
-	interpreter := NewInterpreter()
-	ret, err := interpreter.Interpret(expressions)
+	for name, interpreter := range interpreters() {
+		ret, err := interpreter.Interpret(expressions)
 
-	if err != nil {
-		t.Fatalf("Expected no error, got %v", err)
-	}
+		if err != nil {
+			t.Fatalf("Expected no error from the %s interpreter, got %v", name, err)
+		}
 
-	if ret != "no" {
-		t.Fatalf("Expected 'no' as result, got '%v'", ret)
+		if ret != "no" {
+			t.Fatalf("Expected 'no' as result, got '%v' from the %s interpreter", ret, name)
+		}
 	}
 }
 
@@ -54,6 +59,7 @@ func TestInterpret_ShouldCorrectlyInterpretCode3(t *testing.T) {
 		&DefunExpr{
 			Token{Identifier, "give-outer", 2, nil},
 			[]Token{},
+			"",
 			&VarExpr{Token{Identifier, "outer-name", 2, nil}},
 		},
 		&FuncCallExpr{
@@ -62,15 +68,16 @@ func TestInterpret_ShouldCorrectlyInterpretCode3(t *testing.T) {
 		},
 	}
 
-	interpreter := NewInterpreter()
-	ret, err := interpreter.Interpret(expressions)
+	for name, interpreter := range interpreters() {
+		ret, err := interpreter.Interpret(expressions)
 
-	if err != nil {
-		t.Fatalf("Expected no error, got %v", err)
-	}
+		if err != nil {
+			t.Fatalf("Expected no error from the %s interpreter, got %v", name, err)
+		}
 
-	if ret != "Steven" {
-		t.Fatalf("Expected 'no' as result, got '%v'", ret)
+		if ret != "Steven" {
+			t.Fatalf("Expected 'no' as result, got '%v' from the %s interpreter", ret, name)
+		}
 	}
 }
 
@@ -95,14 +102,323 @@ func TestInterpret_ShouldCorrectlyInterpretCode4(t *testing.T) {
 		},
 	}
 
-	interpreter := NewInterpreter()
-	ret, err := interpreter.Interpret(expressions)
+	for name, interpreter := range interpreters() {
+		ret, err := interpreter.Interpret(expressions)
 
+		if err != nil {
+			t.Fatalf("Expected no error from the %s interpreter, got %v", name, err)
+		}
+
+		if ret != 2 {
+			t.Fatalf("Expected '2' as result, got '%v' from the %s interpreter", ret, name)
+		}
+	}
+}
+
+// configurations are interpreter setups which must behave like the default
+// tree-walking interpreter.
+var configurations = map[string][]Option{
+	"bytecode":           {WithBackend(Bytecode)},
+	"optimized":          {WithOptimization()},
+	"optimized bytecode": {WithBackend(Bytecode), WithOptimization()},
+}
+
+// interpreters returns the default interpreter and an interpreter for every
+// configuration by name.
+func interpreters() map[string]*Interpreter {
+	ret := map[string]*Interpreter{"default": NewInterpreter()}
+
+	for name, options := range configurations {
+		ret[name] = NewInterpreter(options...)
+	}
+
+	return ret
+}
+
+// interpretSource scans, parses and interprets a piece of source code with
+// every configuration.
+func interpretSource(t *testing.T, src string) (interface{}, error) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tokens, ok := NewScanner(src, &buf).Scan()
+	if !ok {
+		t.Fatalf("Expected source to scan, got %s", buf.String())
+	}
+
+	expressions, err := NewParser(tokens).Parse()
 	if err != nil {
+		t.Fatalf("Expected source to parse, got %v", err)
+	}
+
+	ret, err := NewInterpreter().Interpret(expressions)
+
+	for name, options := range configurations {
+		configRet, configErr := NewInterpreter(options...).Interpret(expressions)
+
+		if fmt.Sprint(configRet) != fmt.Sprint(ret) || fmt.Sprint(configErr) != fmt.Sprint(err) {
+			t.Fatalf("Expected %v and %v from the %s interpreter for %s, got %v and %v", ret, err, name, src, configRet, configErr)
+		}
+	}
+
+	return ret, err
+}
+
+// expectResult interprets a piece of source code and compares the printed result.
+func expectResult(t *testing.T, src string, expected string) {
+	t.Helper()
+
+	ret, err := interpretSource(t, src)
+	if err != nil {
+		t.Fatalf("Expected no error for %s, got %v", src, err)
+	}
+
+	if actual := fmt.Sprintf("%v", ret); actual != expected {
+		t.Fatalf("Expected '%s' as result of %s, got '%s'", expected, src, actual)
+	}
+}
+
+// expectError interprets a piece of source code and expects an error.
+func expectError(t *testing.T, src string) error {
+	t.Helper()
+
+	_, err := interpretSource(t, src)
+	if err == nil {
+		t.Fatalf("Expected an error for %s", src)
+	}
+
+	return err
+}
+
+func TestInterpret_ShouldDestructureLetBindings(t *testing.T) {
+	expectResult(t, "(let ((a b) '(1 2) c 3) (+ a b c))", "6")
+	expectResult(t, "(let ((a (b c) &rest d) '(1 '(2 3) 4 5)) '(a b c d))", "(1 2 3 (4 5))")
+	expectResult(t, "(let ((a &rest b) '(1)) b)", "()")
+	expectResult(t, "(let ((a b &rest r) (range)) '(a b (take 2 r)))", "(0 1 (2 3))")
+	expectResult(t, "(let ((a &rest r) (iterate (lambda (x) (* x 2)) 1)) '(a (first r)))", "(1 2)")
+	expectResult(t, "(let ((a b) (take 2 (range))) b)", "1")
+}
+
+func TestInterpret_ShouldDestructureParameters(t *testing.T) {
+	expectResult(t, "(defun f ((a b) c) (+ a b c)) (f '(1 2) 3)", "6")
+	expectResult(t, "(map (lambda ((k v)) v) '('(1 2) '(3 4)))", "(2 4)")
+	expectResult(t, "(defun f (a &rest r) r) (f 1 2 3)", "(2 3)")
+	expectResult(t, "(defun f (&rest r) r) (f)", "()")
+	expectResult(t, "(defun f (&rest (a b)) (+ a b)) (f 1 2)", "3")
+	expectResult(t, "(apply (lambda (&rest r) r) '(1 2))", "(1 2)")
+}
+
+func TestInterpret_ShouldReportPatternMismatches(t *testing.T) {
+	for src, msg := range map[string]string{
+		"(let ((a b) 1) a)":                  "expected a list",
+		"(let ((a b) '(1)) a)":               "expected 2 elements but got 1",
+		"(let ((a b) '(1 2 3)) a)":           "expected 2 elements but got more",
+		"(let ((a b &rest c) '(1)) a)":       "expected at least 2 elements but got 1",
+		"(defun f (a &rest r) r) (f)":        "Expected at least 1 arguments but got 0",
+		"(defun f ((a (b))) b) (f '(1 '()))": "expected 1 elements but got 0",
+	} {
+		err := expectError(t, src)
+		if !strings.Contains(err.Error(), msg) {
+			t.Fatalf("Expected error containing '%s' for %s, got %v", msg, src, err)
+		}
+	}
+}
+
+func TestInterpret_LetEvaluatesValuesInEnclosingScope(t *testing.T) {
+	expectResult(t, "(defvar a 10) (let (a 1 b a) b)", "10")
+	expectError(t, "(let (a 1 b (+ a 1)) b)")
+}
+
+func TestInterpret_LetStarBindsSequentially(t *testing.T) {
+	expectResult(t, "(let* (a 1 b (+ a 1)) b)", "2")
+	expectResult(t, "(let* (a 1 a (+ a 1)) a)", "2")
+	expectResult(t, "(let* (a 1 f (lambda () a) a 2) '(a (f)))", "(2 1)")
+	expectResult(t, "(let* (a '(1 2) (b c) a d (+ b c)) d)", "3")
+	expectResult(t, "(let* ((a b) '(1 2) c (+ a b) (d) '(c)) d)", "3")
+}
+
+func TestInterpret_LetrecBindsRecursively(t *testing.T) {
+	src := `
+	(letrec (even? (lambda (n) (if (= n 0) true (odd? (- n 1))))
+	         odd?  (lambda (n) (if (= n 0) false (even? (- n 1)))))
+	  '((even? 10) (odd? 7) (even? 3)))`
+
+	expectResult(t, src, "(true true false)")
+	expectResult(t, "(letrec (fact (lambda (n) (if (= n 0) 1 (* n (fact (- n 1)))))) (fact 5))", "120")
+}
+
+func TestInterpret_ShouldAllowShadowingBuiltins(t *testing.T) {
+	expectResult(t, "(defvar first \"Christian\") first", "Christian")
+	expectResult(t, "(defun rest (l) \"mine\") (rest '(1 2))", "mine")
+	expectResult(t, "(defvar first 1) (map + '(1) '(2))", "(3)")
+	expectError(t, "(defvar first 1) (defvar first 2)")
+}
+
+func TestInterpret_ShouldWarnWhenShadowingBuiltins(t *testing.T) {
+	var warnings bytes.Buffer
+
+	tokens, _ := NewScanner("(defvar first 1)\n(let (rest 2) rest)\n(defvar mine 3)", &warnings).Scan()
+	expressions, err := NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := NewInterpreter(WithShadowWarnings(&warnings)).Interpret(expressions); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if warnings.String() != "[line 1] Warning: 'first' shadows a builtin\n" {
+		t.Fatalf("Expected a single warning, got '%s'", warnings.String())
+	}
+}
+
+func TestInterpret_ShouldRedefineGlobalsWhenEnabled(t *testing.T) {
+	var redefined []string
+
+	interpreter := NewInterpreter(WithRedefinition(func(name string) {
+		redefined = append(redefined, name)
+	}))
+
+	for _, src := range []string{
+		"(defun greet (name) (+ 1 name))",
+		"(defun greet-twice (name) '((greet name) (greet name)))",
+		"(defun greet (name) (* 2 name))",
+		"(defvar x 1)",
+		"(defvar x 2)",
+	} {
+		tokens, _ := NewScanner(src, &bytes.Buffer{}).Scan()
+		expressions, _ := NewParser(tokens).Parse()
+
+		if _, err := interpreter.Interpret(expressions); err != nil {
+			t.Fatalf("Expected no error for %s, got %v", src, err)
+		}
+	}
+
+	tokens, _ := NewScanner("(greet-twice x)", &bytes.Buffer{}).Scan()
+	expressions, _ := NewParser(tokens).Parse()
+
+	ret, err := interpreter.Interpret(expressions)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if actual := fmt.Sprintf("%v", ret); actual != "(4 4)" {
+		t.Fatalf("Expected '(4 4)' as result, got '%s'", actual)
+	}
+
+	if strings.Join(redefined, " ") != "greet x" {
+		t.Fatalf("Expected greet and x to be redefined, got %v", redefined)
+	}
+}
+
+func TestInterpret_ShouldMatchPatterns(t *testing.T) {
+	describe := `(defun describe (value)
+	  (match value
+	    (nil "nothing")
+	    ("hi" "greeting")
+	    (true "yes")
+	    (() "empty")
+	    ((0) "single zero")
+	    (((? (lambda (x) (> x 0)) x)) "single positive")
+	    ((x) "single")
+	    ((x _ &rest r) r)
+	    (_ "other")))`
+
+	for value, expected := range map[string]string{
+		"nil":          "nothing",
+		`"hi"`:         "greeting",
+		"true":         "yes",
+		"'()":          "empty",
+		"'(0)":         "single zero",
+		"'(5)":         "single positive",
+		"'((- 0 1))":   "single",
+		"'(1 2 3 4)":   "(3 4)",
+		"'(1 2)":       "()",
+		"42":           "other",
+		"(range 1 10)": "(3 4 5 6 7 8 9)",
+	} {
+		expectResult(t, describe+"(describe "+value+")", expected)
+	}
+
+	expectResult(t, "(match (range) ((a) a) ((a b &rest r) '(a b (take 2 r))))", "(0 1 (2 3))")
+}
+
+func TestInterpret_ShouldBindVariablesInGuards(t *testing.T) {
+	expectResult(t, "(match '(1 2) ((a (? (lambda (b) (> b a)) b)) (+ a b)) (_ 0))", "3")
+	expectResult(t, "(match '(2 1) ((a (? (lambda (b) (> b a)) b)) (+ a b)) (_ 0))", "0")
+	expectResult(t, "(defvar x 1) (match 2 (x x)) ", "2")
+	expectResult(t, "(defvar x 1) (match 2 (y x)) ", "1")
+}
+
+func TestInterpret_ShouldReportMissingMatchClause(t *testing.T) {
+	err := expectError(t, "(match '(1 2)\n  ((a) a)\n  (3 3))")
+	if err.Error() != "[line 1] No matching clause for (1 2)" {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	err = expectError(t, "(match 1 ((? 2 x) x))")
+	if !strings.Contains(err.Error(), "Guard 2 is not a function") {
+		t.Fatalf("Unexpected error %v", err)
+	}
+}
+
+func TestInterpreter_ShouldCompleteNames(t *testing.T) {
+	interpreter := NewInterpreter()
+	if _, err := interpreter.Interpret(parse(t, "(defvar length-of-list 1) (defun lenient () 2)")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	completions := interpreter.Completions("len")
+	if fmt.Sprint(completions) != "[len length-of-list lenient]" {
+		t.Fatalf("Expected globals and builtins, got %v", completions)
+	}
+
+	completions = interpreter.Completions("let")
+	if fmt.Sprint(completions) != "[let let* letrec]" {
+		t.Fatalf("Expected keywords, got %v", completions)
+	}
+}
+
+func TestInterpreter_ShouldListGlobals(t *testing.T) {
+	interpreter := NewInterpreter()
+	if _, err := interpreter.Interpret(parse(t, "(defvar b 1) (defun a () b)")); err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
 
-	if ret != 2 {
-		t.Fatalf("Expected '2' as result, got '%v'", ret)
+	if names := interpreter.Globals().Names(); fmt.Sprint(names) != "[a b]" {
+		t.Fatalf("Expected [a b], got %v", names)
+	}
+
+	if names := interpreter.Builtins().Names(); len(names) == 0 {
+		t.Fatalf("Expected builtins")
+	}
+}
+
+func TestInterpret_ShouldExitWithACode(t *testing.T) {
+	err := expectError(t, "(defun f (x) (if (> x 1) (exit 3) x)) (f 1) (f 2) (println \"unreachable\")")
+	if exit, ok := err.(*ExitError); !ok || exit.Code != 3 {
+		t.Fatalf("Expected exit status 3, got %v", err)
+	}
+
+	err = expectError(t, "(pmap (lambda (x) (exit x)) '(4))")
+	if exit, ok := err.(*ExitError); !ok || exit.Code != 4 {
+		t.Fatalf("Expected exit status 4, got %v", err)
+	}
+
+	err = expectError(t, "(sort (lambda (a b) (exit 2)) '(1 2))")
+	if exit, ok := err.(*ExitError); !ok || exit.Code != 2 {
+		t.Fatalf("Expected exit status 2, got %v", err)
+	}
+
+	err = expectError(t, "(exit \"1\")")
+	if _, ok := err.(*ExitError); ok {
+		t.Fatalf("Expected an error for a non-numeric code, got %v", err)
+	}
+
+	for _, src := range []string{"(exit 256)", "(exit (- 0 1))"} {
+		err = expectError(t, src)
+		if !strings.Contains(err.Error(), "Exit code must be between 0 and 255") {
+			t.Fatalf("Expected an error for an out of range code in %s, got %v", src, err)
+		}
 	}
 }
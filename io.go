@@ -1,8 +1,12 @@
 package minimalisp
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
-// Println is just the println function
+// Println is just the println function. It writes to the stream bound to
+// *out*.
 // Usage:
 // (println "Hello" "World") => "Hello World"
 type Println struct{}
@@ -14,15 +18,24 @@ func (p *Println) Arity() int {
 
 // Call implements the println function.
 func (p *Println) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
-	for i, arg := range arguments {
-		if i != 0 {
-			fmt.Print(" ")
+	out, ok := i.builtinValue("*out*").(*Output)
+	if !ok {
+		return nil, &executionError{line, "*out* must be an output stream"}
+	}
+
+	var parts []string
+
+	for _, arg := range arguments {
+		if err := realizePrinted(i, arg); err != nil {
+			return nil, err
 		}
 
-		fmt.Print(arg)
+		parts = append(parts, fmt.Sprint(arg))
 	}
 
-	fmt.Println()
+	if _, err := fmt.Fprintln(out, strings.Join(parts, " ")); err != nil {
+		return nil, &executionError{line, fmt.Sprintf("Could not print: %v", err)}
+	}
 
 	return nil, nil
 }
@@ -30,3 +43,31 @@ func (p *Println) Call(line int, i *Interpreter, arguments []interface{}) (inter
 func (p *Println) String() string {
 	return "<println>"
 }
+
+// Exit stops the script with an exit code.
+// Usage:
+// (exit 1)
+type Exit struct{}
+
+// Arity returns 1 for exit.
+func (e *Exit) Arity() int {
+	return 1
+}
+
+// Call implements the exit function.
+func (e *Exit) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	code, ok := arguments[0].(float64)
+	if !ok || code != float64(int(code)) {
+		return nil, &executionError{line, fmt.Sprintf("Exit code must be an integer, got %v", arguments[0])}
+	}
+
+	if code < 0 || code > 255 {
+		return nil, &executionError{line, fmt.Sprintf("Exit code must be between 0 and 255, got %v", code)}
+	}
+
+	return nil, &ExitError{int(code)}
+}
+
+func (e *Exit) String() string {
+	return "<exit>"
+}
@@ -0,0 +1,601 @@
+package minimalisp
+
+import (
+	"fmt"
+	"sync"
+)
+
+// printLimit is the maximum amount of elements printed for lazy sequences.
+const printLimit = 100
+
+// LazySeq is a list whose elements are only computed when they are needed.
+// The computation is run at most once and its result is memoized. Since the
+// List interface cannot report errors, builtins walk lazy sequences using
+// next, which returns errors raised while realizing the sequence. As a
+// sequence may be realized on any goroutine, the computation evaluates code
+// in a task of its own.
+type LazySeq struct {
+	mutex sync.Mutex
+	thunk func(task *Interpreter) (List, error)
+	// owner is the task which created the sequence. The thunk runs on a
+	// fork of it.
+	owner *Interpreter
+	// line is where the sequence was created.
+	line int
+	// done is closed once the sequence is realized.
+	done chan struct{}
+	seq  List
+	err  error
+}
+
+// NewLazySeq is a factory function to create a new lazy sequence. The thunk
+// returns the realized sequence or nil if the sequence is empty.
+func NewLazySeq(thunk func() (List, error)) *LazySeq {
+	return &LazySeq{thunk: func(*Interpreter) (List, error) {
+		return thunk()
+	}}
+}
+
+// newLazySeq creates a lazy sequence whose thunk runs on a fork of i and
+// which reports errors at a line.
+func newLazySeq(line int, i *Interpreter, thunk func(task *Interpreter) (List, error)) *LazySeq {
+	l := &LazySeq{thunk: thunk, line: line}
+	if i != nil {
+		l.owner = i.fork()
+	}
+
+	return l
+}
+
+// realization is a lazy sequence whose thunk a task runs and the
+// realizations the task was asked for the sequence in.
+type realization struct {
+	seq    *LazySeq
+	parent *realization
+}
+
+// realize runs the thunk once. Other tasks realizing the sequence at the
+// same time wait for it, while the thunk itself asking for the sequence is
+// an error as it would wait for itself. task is the task asking for the
+// sequence or nil.
+func (l *LazySeq) realize(task *Interpreter) (List, error) {
+	l.mutex.Lock()
+
+	if l.done == nil {
+		l.done = make(chan struct{})
+		thunk := l.thunk
+		l.mutex.Unlock()
+
+		seq, err := thunk(l.task(task))
+
+		l.mutex.Lock()
+		l.seq, l.err = seq, err
+		l.thunk = nil
+		l.mutex.Unlock()
+
+		close(l.done)
+		return seq, err
+	}
+
+	done := l.done
+	l.mutex.Unlock()
+
+	select {
+	case <-done:
+	default:
+		if task.isRealizing(l) {
+			return nil, &executionError{l.line, "Lazy sequence depends on itself"}
+		}
+
+		<-done
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.seq, l.err
+}
+
+// task returns the task the thunk runs on. It remembers the sequences the
+// asking task is realizing, so that nested thunks asking for any of them are
+// detected.
+func (l *LazySeq) task(asking *Interpreter) *Interpreter {
+	if l.owner == nil {
+		return nil
+	}
+
+	task := l.owner.fork()
+	task.realizing = &realization{l, nil}
+
+	if asking != nil {
+		task.realizing.parent = asking.realizing
+	}
+
+	return task
+}
+
+// isRealizing returns whether the task runs the thunk of a lazy sequence.
+func (i *Interpreter) isRealizing(l *LazySeq) bool {
+	if i == nil {
+		return false
+	}
+
+	for r := i.realizing; r != nil; r = r.parent {
+		if r.seq == l {
+			return true
+		}
+	}
+
+	return false
+}
+
+// First returns the first element of the lazy sequence.
+func (l *LazySeq) First() interface{} {
+	first, _, _, _ := next(nil, l)
+	return first
+}
+
+// Rest returns all except the first element of the lazy sequence.
+func (l *LazySeq) Rest() List {
+	_, rest, ok, _ := next(nil, l)
+	if !ok {
+		return NewArrayList(nil)
+	}
+
+	return rest
+}
+
+// Add returns a lazy sequence with an element added at the end.
+func (l *LazySeq) Add(el interface{}) List {
+	return lazyConcat(l.line, l.owner, []List{l, NewArrayList([]interface{}{el})})
+}
+
+// Len realizes the whole lazy sequence and returns its length. As Len can
+// not report errors, it panics if realizing the sequence fails. Builtins
+// count lazy sequences with toSlice instead.
+func (l *LazySeq) Len() int {
+	elements, err := toSlice(nil, l)
+	if err != nil {
+		panic(err)
+	}
+
+	return len(elements)
+}
+
+func (l *LazySeq) String() string {
+	return seqString(l)
+}
+
+// Cons is a list made out of a first element and the rest of the list.
+// Unlike ArrayList the rest is not copied, which allows building lazy sequences.
+type Cons struct {
+	first interface{}
+	rest  List
+}
+
+// NewCons is a factory function to create a new cons cell.
+func NewCons(first interface{}, rest List) *Cons {
+	return &Cons{first, rest}
+}
+
+// First returns the first element of the cons cell.
+func (c *Cons) First() interface{} {
+	return c.first
+}
+
+// Rest returns the rest of the cons cell.
+func (c *Cons) Rest() List {
+	return c.rest
+}
+
+// Add returns a list with an element added at the end.
+func (c *Cons) Add(el interface{}) List {
+	return NewCons(c.first, c.rest.Add(el))
+}
+
+// Len returns the length of the list.
+func (c *Cons) Len() int {
+	return 1 + c.rest.Len()
+}
+
+func (c *Cons) String() string {
+	return seqString(c)
+}
+
+// next splits a list into its first element and the rest of the list. If
+// the list is empty ok is false.
+func next(task *Interpreter, list List) (first interface{}, rest List, ok bool, err error) {
+	switch l := list.(type) {
+	case *LazySeq:
+		seq, err := l.realize(task)
+		if err != nil || seq == nil {
+			return nil, nil, false, err
+		}
+
+		return next(task, seq)
+	case *Cons:
+		return l.first, l.rest, true, nil
+	}
+
+	if list.Len() == 0 {
+		return nil, nil, false, nil
+	}
+
+	return list.First(), list.Rest(), true, nil
+}
+
+// isLazy returns whether a list may contain elements which are not realized yet.
+func isLazy(list List) bool {
+	switch list.(type) {
+	case *LazySeq, *Cons:
+		return true
+	}
+
+	return false
+}
+
+// Realize realizes the elements of a value which are printed. String can
+// not report errors, so a value is realized before it is printed to report
+// errors raised while realizing lazy sequences.
+func Realize(value interface{}) error {
+	return realizePrinted(nil, value)
+}
+
+// realizePrinted realizes the printed elements of a value for a task.
+func realizePrinted(task *Interpreter, value interface{}) error {
+	list, ok := value.(List)
+	if !ok {
+		return nil
+	}
+
+	rest := list
+
+	for n := 0; !isLazy(list) || n < printLimit; n++ {
+		first, r, ok, err := next(task, rest)
+		if err != nil || !ok {
+			return err
+		}
+
+		if err := realizePrinted(task, first); err != nil {
+			return err
+		}
+
+		rest = r
+	}
+
+	return nil
+}
+
+// seqString prints a sequence while realizing at most printLimit elements.
+func seqString(list List) string {
+	var ret string = "("
+
+	rest := list
+
+	for n := 0; ; n++ {
+		first, r, ok, err := next(nil, rest)
+		if err != nil || !ok {
+			break
+		}
+
+		if n != 0 {
+			ret += " "
+		}
+
+		if n == printLimit {
+			ret += "..."
+			break
+		}
+
+		ret += fmt.Sprintf("%v", first)
+		rest = r
+	}
+
+	return ret + ")"
+}
+
+// lazyConcat lazily joins lists.
+func lazyConcat(line int, i *Interpreter, lists []List) List {
+	return newLazySeq(line, i, func(task *Interpreter) (List, error) {
+		for n, list := range lists {
+			first, rest, ok, err := next(task, list)
+			if err != nil {
+				return nil, err
+			}
+
+			if ok {
+				remaining := append([]List{rest}, lists[n+1:]...)
+				return NewCons(first, lazyConcat(line, task, remaining)), nil
+			}
+		}
+
+		return nil, nil
+	})
+}
+
+// lazyMap lazily applies a function to the elements of lists.
+func lazyMap(line int, i *Interpreter, fun Function, lists []List, index int, indexed bool) List {
+	return newLazySeq(line, i, func(task *Interpreter) (List, error) {
+		var args []interface{}
+		var rests []List
+
+		if indexed {
+			args = append(args, float64(index))
+		}
+
+		for _, list := range lists {
+			first, rest, ok, err := next(task, list)
+			if err != nil || !ok {
+				return nil, err
+			}
+
+			args = append(args, first)
+			rests = append(rests, rest)
+		}
+
+		newEl, err := fun.Call(line, task, args)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewCons(newEl, lazyMap(line, task, fun, rests, index+1, indexed)), nil
+	})
+}
+
+// lazyFilter lazily keeps the elements for which a function returns true.
+func lazyFilter(line int, i *Interpreter, fun Function, list List) List {
+	return newLazySeq(line, i, func(task *Interpreter) (List, error) {
+		rest := list
+
+		for {
+			first, r, ok, err := next(task, rest)
+			if err != nil || !ok {
+				return nil, err
+			}
+
+			response, err := fun.Call(line, task, []interface{}{first})
+			if err != nil {
+				return nil, err
+			}
+
+			if isTruthy(response) {
+				return NewCons(first, lazyFilter(line, task, fun, r)), nil
+			}
+
+			rest = r
+		}
+	})
+}
+
+// lazyDrop lazily drops the first n elements of a list.
+func lazyDrop(line int, i *Interpreter, n int, list List) List {
+	return newLazySeq(line, i, func(task *Interpreter) (List, error) {
+		rest := list
+
+		for ; n > 0; n-- {
+			_, r, ok, err := next(task, rest)
+			if err != nil || !ok {
+				return nil, err
+			}
+
+			rest = r
+		}
+
+		_, _, ok, err := next(task, rest)
+		if err != nil || !ok {
+			return nil, err
+		}
+
+		return rest, nil
+	})
+}
+
+// lazyZip lazily combines the elements of lists into tuples.
+func lazyZip(line int, i *Interpreter, lists []List) List {
+	return newLazySeq(line, i, func(task *Interpreter) (List, error) {
+		var tuple []interface{}
+		var rests []List
+
+		for _, list := range lists {
+			first, rest, ok, err := next(task, list)
+			if err != nil || !ok {
+				return nil, err
+			}
+
+			tuple = append(tuple, first)
+			rests = append(rests, rest)
+		}
+
+		return NewCons(NewArrayList(tuple), lazyZip(line, task, rests)), nil
+	})
+}
+
+// LazySeqBuiltin creates a lazy sequence out of a function without
+// parameters which returns a list or nil.
+// Usage:
+// (defun ones () (lazy-seq (lambda () (cons 1 (ones)))))
+type LazySeqBuiltin struct{}
+
+// Arity returns 1.
+func (f *LazySeqBuiltin) Arity() int {
+	return 1
+}
+
+// Call implements lazy-seq.
+func (f *LazySeqBuiltin) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	fun, err := expectFunction(line, "lazy-seq", arguments[0], 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return newLazySeq(line, i, func(task *Interpreter) (List, error) {
+		ret, err := fun.Call(line, task, nil)
+		if err != nil || ret == nil {
+			return nil, err
+		}
+
+		list, ok := ret.(List)
+		if !ok {
+			return nil, &executionError{line, "<lazy-seq> expects the function to return a list or nil"}
+		}
+
+		return list, nil
+	}), nil
+}
+
+func (f *LazySeqBuiltin) String() string {
+	return "<lazy-seq>"
+}
+
+// ConsBuiltin prepends an element to a list without realizing it.
+// Usage:
+// (cons 1 '(2 3)) => (1 2 3)
+type ConsBuiltin struct{}
+
+// Arity returns 2.
+func (f *ConsBuiltin) Arity() int {
+	return 2
+}
+
+// Call implements cons.
+func (f *ConsBuiltin) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	if arguments[1] == nil {
+		return NewArrayList([]interface{}{arguments[0]}), nil
+	}
+
+	list, ok := arguments[1].(List)
+	if !ok {
+		return nil, &executionError{line, "<cons> expects a list or nil as second parameter"}
+	}
+
+	return NewCons(arguments[0], list), nil
+}
+
+func (f *ConsBuiltin) String() string {
+	return "<cons>"
+}
+
+// Iterate returns the infinite lazy sequence x, (f x), (f (f x)), ...
+type Iterate struct{}
+
+// Arity returns 2.
+func (f *Iterate) Arity() int {
+	return 2
+}
+
+// Call implements iterate.
+func (f *Iterate) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	fun, err := expectFunction(line, "iterate", arguments[0], 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return iterate(line, i, fun, arguments[1]), nil
+}
+
+func (f *Iterate) String() string {
+	return "<iterate>"
+}
+
+func iterate(line int, i *Interpreter, fun Function, x interface{}) List {
+	return NewCons(x, newLazySeq(line, i, func(task *Interpreter) (List, error) {
+		ret, err := fun.Call(line, task, []interface{}{x})
+		if err != nil {
+			return nil, err
+		}
+
+		return iterate(line, task, fun, ret), nil
+	}))
+}
+
+// Repeat returns a lazy sequence which repeats a value forever or n times.
+// Usage:
+// (repeat 1) => (1 1 1 ...)
+// (repeat 2 1) => (1 1)
+type Repeat struct{}
+
+// Arity returns infiniteArity as the amount of repetitions is optional.
+func (f *Repeat) Arity() int {
+	return infiniteArity
+}
+
+// Call implements repeat.
+func (f *Repeat) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	switch len(arguments) {
+	case 1:
+		return repeat(arguments[0], -1), nil
+	case 2:
+		n, err := expectIndex(line, "repeat", arguments[0])
+		if err != nil {
+			return nil, err
+		}
+
+		return repeat(arguments[1], n), nil
+	}
+
+	return nil, &executionError{line, "<repeat> expects an optional amount and a value"}
+}
+
+func (f *Repeat) String() string {
+	return "<repeat>"
+}
+
+// repeat lazily repeats a value n times or forever if n is negative.
+func repeat(x interface{}, n int) List {
+	return NewLazySeq(func() (List, error) {
+		if n == 0 {
+			return nil, nil
+		}
+
+		return NewCons(x, repeat(x, n-1)), nil
+	})
+}
+
+// Cycle returns an infinite lazy sequence repeating the elements of a list.
+type Cycle struct{}
+
+// Arity returns 1.
+func (f *Cycle) Arity() int {
+	return 1
+}
+
+// Call implements cycle.
+func (f *Cycle) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	list, ok := arguments[0].(List)
+	if !ok {
+		return nil, &executionError{line, "<cycle> is only defined for lists"}
+	}
+
+	return cycle(line, i, list, list), nil
+}
+
+func (f *Cycle) String() string {
+	return "<cycle>"
+}
+
+func cycle(line int, i *Interpreter, list List, rest List) List {
+	return newLazySeq(line, i, func(task *Interpreter) (List, error) {
+		first, r, ok, err := next(task, rest)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			// An empty list can not be cycled.
+			if rest == list {
+				return nil, nil
+			}
+
+			return cycle(line, task, list, list), nil
+		}
+
+		return NewCons(first, cycle(line, task, list, r)), nil
+	})
+}
+
+// lazyRange returns an infinite lazy sequence of numbers.
+func lazyRange(start float64, step float64) List {
+	return NewLazySeq(func() (List, error) {
+		return NewCons(start, lazyRange(start+step, step)), nil
+	})
+}
@@ -0,0 +1,158 @@
+package minimalisp_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "bakku.dev/minimalisp"
+)
+
+func TestLazy_InfiniteRange(t *testing.T) {
+	expectResult(t, "(take 3 (range))", "(0 1 2)")
+	expectResult(t, "(take 3 (filter (lambda (x) (> x 3)) (range)))", "(4 5 6)")
+	expectResult(t, "(nth (map + (range) (range)) 10)", "20")
+	expectResult(t, "(take 2 (drop 5 (range)))", "(5 6)")
+	expectResult(t, "(find (lambda (x) (> x 41)) (range))", "42")
+	expectResult(t, "(index-of (range) 7)", "7")
+	expectResult(t, "(take 2 (zip (range) '(\"a\" \"b\" \"c\")))", "((0 a) (1 b))")
+	expectResult(t, "(take 3 (map-indexed (lambda (i x) (* i x)) (range)))", "(0 1 4)")
+}
+
+func TestLazy_Constructors(t *testing.T) {
+	expectResult(t, "(take 4 (iterate (lambda (x) (* x 2)) 1))", "(1 2 4 8)")
+	expectResult(t, "(take 3 (repeat \"a\"))", "(a a a)")
+	expectResult(t, "(repeat 2 1)", "(1 1)")
+	expectResult(t, "(take 5 (cycle '(1 2)))", "(1 2 1 2 1)")
+	expectResult(t, "(cycle '())", "()")
+	expectResult(t, "(cons 1 '(2 3))", "(1 2 3)")
+	expectResult(t, "(defun ones () (lazy-seq (lambda () (cons 1 (ones))))) (take 3 (ones))", "(1 1 1)")
+	expectResult(t, "(defun ones () (lazy-seq (lambda () (cons 1 (ones))))) (reduce + (take 100 (ones)))", "100")
+}
+
+func TestLazy_RealizesOnce(t *testing.T) {
+	src := `
+	(defun countdown (n)
+	  (lazy-seq (lambda ()
+	    (if (= n 0)
+	      nil
+	      (cons n (countdown (- n 1)))))))
+	(defvar s (countdown 3))
+	(concat s s (reverse s))`
+
+	expectResult(t, src, "(3 2 1 3 2 1 1 2 3)")
+}
+
+func TestLazy_PropagatesErrors(t *testing.T) {
+	err := expectError(t, "(take 3 (map (lambda (x) (/ 1 x)) (range)))")
+	if !strings.Contains(err.Error(), "Division by zero") {
+		t.Fatalf("Expected division by zero error, got %v", err)
+	}
+
+	expectError(t, "(first (lazy-seq (lambda () 1)))")
+}
+
+func TestLazy_PrintsInfiniteSequences(t *testing.T) {
+	ret, err := interpretSource(t, "(range)")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if actual := ret.(interface{ String() string }).String(); !strings.HasSuffix(actual, " 99 ...)") {
+		t.Fatalf("Expected truncated sequence, got %s", actual)
+	}
+}
+
+func TestLazy_ReportsErrorsOfFailingThunks(t *testing.T) {
+	for _, src := range []string{
+		"(println (lazy-seq (lambda () (/ 1 0))))",
+		"(println '(1 (lazy-seq (lambda () (/ 1 0)))))",
+		"(len (lazy-seq (lambda () (/ 1 0))))",
+		"(rest (lazy-seq (lambda () (/ 1 0))))",
+		"(defvar s (lazy-seq (lambda () (/ 1 0)))) (first s) (println s)",
+	} {
+		err := expectError(t, src)
+		if !strings.Contains(err.Error(), "Division by zero") {
+			t.Fatalf("Expected division by zero error for %s, got %v", src, err)
+		}
+	}
+
+	err := expectError(t, "(println (lazy-seq (lambda () (exit 4))))")
+	if exit, ok := err.(*ExitError); !ok || exit.Code != 4 {
+		t.Fatalf("Expected exit status 4, got %v", err)
+	}
+}
+
+func TestLazy_RealizeReportsErrorsOfResults(t *testing.T) {
+	for _, src := range []string{
+		"(iterate (lambda (x) (/ x 0)) 1)",
+		"(lazy-seq (lambda () (/ 1 0)))",
+		"(add (lazy-seq (lambda () (/ 1 0))) 1)",
+	} {
+		ret, err := interpretSource(t, src)
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", src, err)
+		}
+
+		if err := Realize(ret); err == nil || !strings.Contains(err.Error(), "Division by zero") {
+			t.Fatalf("Expected division by zero error for %s, got %v", src, err)
+		}
+	}
+
+	ret, err := interpretSource(t, "(range)")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := Realize(ret); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestLazy_ReportsSelfReferentialSequences(t *testing.T) {
+	for _, src := range []string{
+		"(letrec (s (lazy-seq (lambda () (rest s)))) (first s))",
+		"(letrec (s (lazy-seq (lambda () (drop 1 s)))) (first s))",
+		"(letrec (a (lazy-seq (lambda () (rest b))) b (lazy-seq (lambda () (rest a)))) (first a))",
+		"(letrec (s (lazy-seq (lambda () (let (x (println s)) '(1))))) (first s))",
+		"(letrec (s (lazy-seq (lambda () (first (map + s))))) (first s))",
+	} {
+		err := expectError(t, src)
+		if !strings.Contains(err.Error(), "Lazy sequence depends on itself") {
+			t.Fatalf("Expected self reference error for %s, got %v", src, err)
+		}
+	}
+
+	ret, err := interpretSource(t, "(letrec (s (lazy-seq (lambda () (drop 1 s)))) s)")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := Realize(ret); err == nil || !strings.Contains(err.Error(), "Lazy sequence depends on itself") {
+		t.Fatalf("Expected self reference error, got %v", err)
+	}
+}
+
+func TestLazy_ConcurrentRealizersWait(t *testing.T) {
+	src := `
+	(defvar calls (atom 0))
+	(defvar s (lazy-seq (lambda () (let (n (swap! calls (lambda (n) (+ n 1)))) '(1 2)))))
+	(defvar results (pmap (lambda (n) (first s)) '(1 2 3 4 5 6 7 8)))
+	'(results (deref calls))`
+
+	expectResult(t, src, "((1 1 1 1 1 1 1 1) 1)")
+}
+
+func TestLazy_LenPanicsOnErrors(t *testing.T) {
+	seq := NewLazySeq(func() (List, error) {
+		return nil, errors.New("failed")
+	})
+
+	defer func() {
+		if err, ok := recover().(error); !ok || err.Error() != "failed" {
+			t.Fatalf("Expected Len to panic with the error, got %v", err)
+		}
+	}()
+
+	seq.Len()
+}
@@ -25,9 +25,11 @@ func (a *ArrayList) Rest() List {
 	return NewArrayList(a.elements[1:])
 }
 
-// Add returns all except the first element of the ArrayList
+// Add returns a new ArrayList with the element added at the end. The
+// elements are copied so that lists never share their backing array.
 func (a *ArrayList) Add(el interface{}) List {
-	newElements := a.elements
+	newElements := make([]interface{}, len(a.elements), len(a.elements)+1)
+	copy(newElements, a.elements)
 
 	newElements = append(newElements, el)
 
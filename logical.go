@@ -229,7 +229,7 @@ func (f *Eq) Call(line int, i *Interpreter, arguments []interface{}) (interface{
 			break
 		}
 
-		if arg != arguments[i+1] {
+		if !equal(arg, arguments[i+1]) {
 			return false, nil
 		}
 	}
@@ -260,7 +260,7 @@ func (f *NotEq) Call(line int, i *Interpreter, arguments []interface{}) (interfa
 			break
 		}
 
-		if arg == arguments[i+1] {
+		if equal(arg, arguments[i+1]) {
 			return false, nil
 		}
 	}
@@ -288,3 +288,25 @@ func (f *Not) Call(line int, i *Interpreter, arguments []interface{}) (interface
 func (f *Not) String() string {
 	return "<!>"
 }
+
+// equal compares two values. Records are equal if they have the same type
+// and equal field values.
+func equal(a, b interface{}) bool {
+	ra, ok := a.(*Record)
+	if !ok {
+		return a == b
+	}
+
+	rb, ok := b.(*Record)
+	if !ok || ra.recordType != rb.recordType {
+		return false
+	}
+
+	for n := range ra.values {
+		if !equal(ra.values[n], rb.values[n]) {
+			return false
+		}
+	}
+
+	return true
+}
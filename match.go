@@ -0,0 +1,124 @@
+package minimalisp
+
+import "fmt"
+
+// matcher checks whether a value matches a pattern and binds the variables
+// of the pattern in env.
+type matcher func(i *Interpreter, line int, value interface{}, env *Environment) (bool, error)
+
+// compilePattern turns a pattern into a matcher so that the structure of the
+// pattern only has to be inspected once.
+func compilePattern(pattern Pattern) matcher {
+	switch p := pattern.(type) {
+	case *WildcardPattern:
+		return func(i *Interpreter, line int, value interface{}, env *Environment) (bool, error) {
+			return true, nil
+		}
+	case *LiteralPattern:
+		return func(i *Interpreter, line int, value interface{}, env *Environment) (bool, error) {
+			return equal(p.Value, value), nil
+		}
+	case *BindingPattern:
+		return func(i *Interpreter, line int, value interface{}, env *Environment) (bool, error) {
+			return true, env.Define(p.Name, value)
+		}
+	case *ListPattern:
+		return compileListPattern(p)
+	case *GuardPattern:
+		inner := compilePattern(p.Pattern)
+
+		return func(i *Interpreter, line int, value interface{}, env *Environment) (bool, error) {
+			pred, err := i.execute(p.Predicate, env)
+			if err != nil {
+				return false, err
+			}
+
+			fun, ok := pred.(Function)
+			if !ok {
+				return false, &executionError{p.Paren.Line, fmt.Sprintf("Guard %v is not a function", pred)}
+			}
+
+			ret, err := callFunction(p.Paren.Line, i, fun, []interface{}{value})
+			if err != nil || !isTruthy(ret) {
+				return false, err
+			}
+
+			return inner(i, line, value, env)
+		}
+	}
+
+	panic(fmt.Sprintf("unknown pattern %T", pattern))
+}
+
+func compileListPattern(p *ListPattern) matcher {
+	var elements []matcher
+
+	for _, el := range p.Elements {
+		elements = append(elements, compilePattern(el))
+	}
+
+	var rest matcher
+
+	if p.Rest != nil {
+		rest = compilePattern(p.Rest)
+	}
+
+	return func(i *Interpreter, line int, value interface{}, env *Environment) (bool, error) {
+		list, ok := value.(List)
+		if !ok {
+			return false, nil
+		}
+
+		remaining := list
+
+		for _, el := range elements {
+			first, r, ok, err := next(i, remaining)
+			if err != nil || !ok {
+				return false, err
+			}
+
+			if matched, err := el(i, line, first, env); err != nil || !matched {
+				return false, err
+			}
+
+			remaining = r
+		}
+
+		if rest != nil {
+			return rest(i, line, remaining, env)
+		}
+
+		_, _, ok, err := next(i, remaining)
+		return !ok, err
+	}
+}
+
+func (i *Interpreter) visitMatchExpr(matchExpr *MatchExpr) (interface{}, error) {
+	matchExpr.compile.Do(func() {
+		for _, clause := range matchExpr.Clauses {
+			matchExpr.matchers = append(matchExpr.matchers, compilePattern(clause.Pattern))
+		}
+	})
+
+	val, err := matchExpr.Value.Accept(i)
+	if err != nil {
+		return nil, err
+	}
+
+	line := matchExpr.Keyword.Line
+
+	for n, clause := range matchExpr.Clauses {
+		env := NewEnvironmentWithEnclosing(i.current)
+
+		matched, err := matchExpr.matchers[n](i, line, val, env)
+		if err != nil {
+			return nil, err
+		}
+
+		if matched {
+			return i.execute(clause.Body, env)
+		}
+	}
+
+	return nil, &executionError{line, fmt.Sprintf("No matching clause for %v", val)}
+}
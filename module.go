@@ -0,0 +1,305 @@
+package minimalisp
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// moduleExtension is the file extension of Minimalisp source files.
+const moduleExtension = ".mlisp"
+
+// Module is a loaded source file with its own environment. Only exported
+// variables can be accessed from outside using qualified names like util/helper.
+type Module struct {
+	Name    string
+	Path    string
+	env     *Environment
+	exports []Token
+}
+
+// Get returns an exported variable of the module.
+func (m *Module) Get(token Token, name string) (interface{}, error) {
+	for _, export := range m.exports {
+		if export.Lexeme == name {
+			return m.env.Get(Token{Identifier, name, token.Line, nil})
+		}
+	}
+
+	return nil, &executionError{token.Line, fmt.Sprintf("Module '%s' does not export '%s'", m.Name, name)}
+}
+
+func (m *Module) String() string {
+	return "<module " + m.Name + ">"
+}
+
+// moduleCache stores loaded modules by their absolute path.
+type moduleCache struct {
+	mutex   sync.Mutex
+	modules map[string]*Module
+}
+
+func (c *moduleCache) get(path string) (*Module, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	mod, ok := c.modules[path]
+	return mod, ok
+}
+
+// add caches a module. If another task loaded the same module in the
+// meantime, the module cached first is returned.
+func (c *moduleCache) add(mod *Module) *Module {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if cached, ok := c.modules[mod.Path]; ok {
+		return cached
+	}
+
+	c.modules[mod.Path] = mod
+	return mod
+}
+
+// InterpretScript interprets expressions which were read from a file.
+// Relative paths given to load and require are resolved against the
+// directory of the file.
+func (i *Interpreter) InterpretScript(filename string, expressions []Expression) (interface{}, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	task := i.fork()
+	task.files = append(task.files, abs)
+
+	return task.interpret(expressions)
+}
+
+func (i *Interpreter) visitModuleExpr(moduleExpr *ModuleExpr) (interface{}, error) {
+	if i.module != nil {
+		i.module.Name = moduleExpr.Name.Lexeme
+		i.module.exports = moduleExpr.Exports
+	}
+
+	return nil, nil
+}
+
+func (i *Interpreter) visitRequireExpr(requireExpr *RequireExpr) (interface{}, error) {
+	mod, err := i.require(requireExpr.Keyword.Line, requireExpr.Path.Value.(string))
+	if err != nil {
+		return nil, err
+	}
+
+	alias := requireExpr.Alias
+	if alias.Lexeme == "" {
+		alias = Token{Identifier, mod.Name, requireExpr.Keyword.Line, nil}
+	}
+
+	if bound, err := i.current.Get(alias); err == nil && bound == mod {
+		return mod, nil
+	}
+
+	if err := i.current.Define(alias, mod); err != nil {
+		return nil, err
+	}
+
+	return mod, nil
+}
+
+// lookup returns the value of a variable. Dynamic variables are resolved to
+// their current binding.
+func (i *Interpreter) lookup(name Token) (interface{}, error) {
+	val, err := i.lookupVar(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if v, ok := val.(*DynamicVar); ok {
+		return i.dynamicValue(v), nil
+	}
+
+	return val, nil
+}
+
+// lookupVar returns the value of a variable. Qualified names like
+// util/helper refer to the exports of a required module.
+func (i *Interpreter) lookupVar(name Token) (interface{}, error) {
+	val, err := i.current.Get(name)
+	if err == nil {
+		return val, nil
+	}
+
+	slash := strings.Index(name.Lexeme, "/")
+	if slash <= 0 || slash == len(name.Lexeme)-1 {
+		return nil, err
+	}
+
+	alias := Token{Identifier, name.Lexeme[:slash], name.Line, nil}
+
+	if val, aliasErr := i.current.Get(alias); aliasErr == nil {
+		if mod, ok := val.(*Module); ok {
+			return mod.Get(name, name.Lexeme[slash+1:])
+		}
+	}
+
+	return nil, err
+}
+
+// require loads a module once and returns it.
+func (i *Interpreter) require(line int, path string) (*Module, error) {
+	filename, err := i.resolvePath(line, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if mod, ok := i.modules.get(filename); ok {
+		return mod, nil
+	}
+
+	env := NewEnvironmentWithEnclosing(i.builtins)
+	name := strings.TrimSuffix(filepath.Base(filename), moduleExtension)
+	mod := &Module{Name: name, Path: filename, env: env}
+
+	prevGlobals, prevModule := i.globals, i.module
+	i.globals, i.module = env, mod
+
+	_, err = i.loadFile(line, filename)
+
+	i.globals, i.module = prevGlobals, prevModule
+
+	if err != nil {
+		return nil, err
+	}
+
+	if mod.exports == nil {
+		for _, name := range env.Names() {
+			mod.exports = append(mod.exports, Token{Identifier, name, line, nil})
+		}
+	}
+
+	for _, export := range mod.exports {
+		if _, err := env.Get(export); err != nil {
+			return nil, &executionError{export.Line, fmt.Sprintf("Module '%s' exports undefined variable '%s'", mod.Name, export.Lexeme)}
+		}
+	}
+
+	return i.modules.add(mod), nil
+}
+
+// loadFile evaluates a file in the current global environment.
+func (i *Interpreter) loadFile(line int, filename string) (interface{}, error) {
+	for n, loading := range i.files {
+		if loading == filename {
+			cycle := append(append([]string{}, i.files[n:]...), filename)
+			return nil, &executionError{line, fmt.Sprintf("Cyclic dependency: %s", strings.Join(cycle, " -> "))}
+		}
+	}
+
+	expressions, err := readSource(line, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if expressions, err = i.prepare(expressions); err != nil {
+		return nil, err
+	}
+
+	i.files = append(i.files, filename)
+	defer func() { i.files = i.files[:len(i.files)-1] }()
+
+	prevEnv := i.current
+	i.current = i.globals
+	defer func() { i.current = prevEnv }()
+
+	return i.evaluate(expressions)
+}
+
+// resolvePath finds a source file relative to the file currently being
+// interpreted, the working directory or one of the directories in MLISP_PATH.
+func (i *Interpreter) resolvePath(line int, path string) (string, error) {
+	if filepath.Ext(path) == "" {
+		path += moduleExtension
+	}
+
+	var candidates []string
+
+	if filepath.IsAbs(path) {
+		candidates = append(candidates, path)
+	} else {
+		if len(i.files) > 0 {
+			candidates = append(candidates, filepath.Join(filepath.Dir(i.files[len(i.files)-1]), path))
+		}
+
+		candidates = append(candidates, path)
+
+		for _, dir := range filepath.SplitList(os.Getenv("MLISP_PATH")) {
+			if dir != "" {
+				candidates = append(candidates, filepath.Join(dir, path))
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return filepath.Abs(candidate)
+		}
+	}
+
+	return "", &executionError{line, fmt.Sprintf("Could not find '%s'", path)}
+}
+
+// readSource scans and parses a source file.
+func readSource(line int, filename string) ([]Expression, error) {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, &executionError{line, fmt.Sprintf("Could not read '%s': %v", filename, err)}
+	}
+
+	var out bytes.Buffer
+
+	tokens, ok := NewScanner(string(src), &out).Scan()
+	if !ok {
+		return nil, &executionError{line, fmt.Sprintf("Could not scan '%s': %s", filename, strings.TrimSpace(out.String()))}
+	}
+
+	expressions, err := NewParser(tokens).Parse()
+	if err != nil {
+		return nil, &executionError{line, fmt.Sprintf("Could not parse '%s': %v", filename, err)}
+	}
+
+	return expressions, nil
+}
+
+// Load evaluates a source file in the current global environment.
+// Usage:
+// (load "helpers.mlisp")
+type Load struct{}
+
+// Arity returns 1.
+func (f *Load) Arity() int {
+	return 1
+}
+
+// Call implements load.
+func (f *Load) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	path, ok := arguments[0].(string)
+	if !ok {
+		return nil, &executionError{line, "<load> expects a path as first parameter"}
+	}
+
+	filename, err := i.resolvePath(line, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.loadFile(line, filename)
+}
+
+func (f *Load) String() string {
+	return "<load>"
+}
@@ -0,0 +1,172 @@
+package minimalisp_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "bakku.dev/minimalisp"
+)
+
+// writeFiles creates files with the given contents in a temporary directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	return dir
+}
+
+// interpretScript interprets a script which was written by writeFiles with
+// every configuration.
+func interpretScript(t *testing.T, filename string) (interface{}, error) {
+	t.Helper()
+
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	tokens, ok := NewScanner(string(src), &buf).Scan()
+	if !ok {
+		t.Fatalf("Expected source to scan, got %s", buf.String())
+	}
+
+	expressions, err := NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Expected source to parse, got %v", err)
+	}
+
+	ret, err := NewInterpreter().InterpretScript(filename, expressions)
+
+	for name, options := range configurations {
+		configRet, configErr := NewInterpreter(options...).InterpretScript(filename, expressions)
+
+		if fmt.Sprint(configRet) != fmt.Sprint(ret) || fmt.Sprint(configErr) != fmt.Sprint(err) {
+			t.Fatalf("Expected %v and %v from the %s interpreter for %s, got %v and %v", ret, err, name, filename, configRet, configErr)
+		}
+	}
+
+	return ret, err
+}
+
+func TestModule_LoadEvaluatesFileInCurrentEnvironment(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.mlisp":    `(load "helpers") (double 21)`,
+		"helpers.mlisp": `(defun double (n) (* n 2))`,
+	})
+
+	ret, err := interpretScript(t, filepath.Join(dir, "main.mlisp"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if ret != 42.0 {
+		t.Fatalf("Expected '42' as result, got '%v'", ret)
+	}
+}
+
+func TestModule_RequireBindsExportsToAlias(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.mlisp": `
+			(require "lib/util" :as u)
+			(require "lib/util" :as u)
+			(defvar secret "main")
+			'((u/helper 1) u/answer (u/twice 2) secret)`,
+		"lib/util.mlisp": `
+			(module util (export helper answer twice))
+			(defvar secret "util")
+			(defvar answer 42)
+			(defun twice (n) (helper (helper n)))
+			(defun helper (n) (+ n 1))`,
+	})
+
+	ret, err := interpretScript(t, filepath.Join(dir, "main.mlisp"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if actual := fmt.Sprintf("%v", ret); actual != "(2 42 4 main)" {
+		t.Fatalf("Expected '(2 42 4 main)' as result, got '%s'", actual)
+	}
+}
+
+func TestModule_RequireWithoutAliasUsesModuleName(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.mlisp":    `(require "strings") (require "math") '(text/greeting math/pi)`,
+		"strings.mlisp": `(module text (export greeting)) (defvar greeting "hi")`,
+		"math.mlisp":    `(defvar pi 3)`,
+	})
+
+	ret, err := interpretScript(t, filepath.Join(dir, "main.mlisp"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if actual := fmt.Sprintf("%v", ret); actual != "(hi 3)" {
+		t.Fatalf("Expected '(hi 3)' as result, got '%s'", actual)
+	}
+}
+
+func TestModule_ShouldNotExposeUnexportedVariables(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.mlisp": `(require "util" :as u) u/secret`,
+		"util.mlisp": `(module util (export)) (defvar secret 1)`,
+	})
+
+	_, err := interpretScript(t, filepath.Join(dir, "main.mlisp"))
+	if err == nil || !strings.Contains(err.Error(), "does not export 'secret'") {
+		t.Fatalf("Expected export error, got %v", err)
+	}
+}
+
+func TestModule_ShouldUseSearchPath(t *testing.T) {
+	lib := writeFiles(t, map[string]string{
+		"shared.mlisp": `(defvar value "shared")`,
+	})
+	dir := writeFiles(t, map[string]string{
+		"main.mlisp": `(require "shared") shared/value`,
+	})
+
+	prev := os.Getenv("MLISP_PATH")
+	os.Setenv("MLISP_PATH", lib)
+	defer os.Setenv("MLISP_PATH", prev)
+
+	ret, err := interpretScript(t, filepath.Join(dir, "main.mlisp"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if ret != "shared" {
+		t.Fatalf("Expected 'shared' as result, got '%v'", ret)
+	}
+}
+
+func TestModule_ShouldDetectCycles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.mlisp": `(require "b")`,
+		"b.mlisp": `(require "a")`,
+	})
+
+	_, err := interpretScript(t, filepath.Join(dir, "a.mlisp"))
+	if err == nil || !strings.Contains(err.Error(), "Cyclic dependency") {
+		t.Fatalf("Expected cyclic dependency error, got %v", err)
+	}
+}
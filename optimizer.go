@@ -0,0 +1,437 @@
+package minimalisp
+
+import "reflect"
+
+// pureBuiltins are the builtins whose calls are folded if all arguments are
+// constants.
+var pureBuiltins = map[string]bool{
+	"+": true, "-": true, "*": true, "/": true,
+	"<": true, "<=": true, ">": true, ">=": true, "=": true, "!=": true,
+	"!": true, "and": true, "or": true,
+}
+
+// WithOptimization makes the interpreter optimize code before running it.
+// See Optimize for the optimizations.
+func WithOptimization() Option {
+	return func(i *Interpreter) {
+		i.optimize = true
+	}
+}
+
+// optimizer rewrites expressions into simpler ones which evaluate to the
+// same values. Like the resolver, it returns copies of the expressions.
+type optimizer struct {
+	interpreter *Interpreter
+	// defined contains the globals which the optimized code defines.
+	defined map[string]bool
+	// loads is set if the optimized code may load files, which can
+	// shadow builtins.
+	loads  bool
+	scopes []map[string]*substitution
+}
+
+// substitution describes a local variable. If the variable is bound to a
+// constant, its references are replaced with the constant.
+type substitution struct {
+	constant *LiteralExpr
+	// pinned is set if the variable is referred to by name, for example
+	// as a function to call, so that its binding must be kept.
+	pinned bool
+}
+
+// Optimize folds calls of arithmetic and comparison builtins whose
+// arguments are constants, eliminates branches of ifs with constant
+// conditions and inlines let bindings of constants. Builtins which are
+// shadowed by a local variable, a definition in the expressions or an
+// existing global are not folded. As a loaded file may shadow builtins, no
+// calls are folded in expressions which refer to load.
+func (i *Interpreter) Optimize(expressions []Expression) []Expression {
+	o := &optimizer{interpreter: i, defined: make(map[string]bool)}
+
+	for _, expr := range expressions {
+		for _, name := range definedNames(expr) {
+			o.defined[name] = true
+		}
+
+		o.loads = o.loads || mentions(reflect.ValueOf(expr), "load")
+	}
+
+	var optimized []Expression
+
+	for _, expr := range expressions {
+		optimized = append(optimized, o.optimize(expr))
+	}
+
+	return optimized
+}
+
+// mentions returns whether an identifier occurs anywhere in an expression.
+func mentions(v reflect.Value, name string) bool {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return false
+		}
+
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Type() == tokenType:
+		token := v.Interface().(Token)
+		return token.TokenType == Identifier && token.Lexeme == name
+	case v.Kind() == reflect.Struct:
+		for n := 0; n < v.NumField(); n++ {
+			if v.Type().Field(n).PkgPath == "" && mentions(v.Field(n), name) {
+				return true
+			}
+		}
+	case v.Kind() == reflect.Slice:
+		for n := 0; n < v.Len(); n++ {
+			if mentions(v.Index(n), name) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (o *optimizer) optimize(expr Expression) Expression {
+	ret, _ := expr.Accept(o)
+	return ret.(Expression)
+}
+
+func (o *optimizer) optimizeAll(expressions []Expression) []Expression {
+	var optimized []Expression
+
+	for _, expr := range expressions {
+		optimized = append(optimized, o.optimize(expr))
+	}
+
+	return optimized
+}
+
+func (o *optimizer) beginScope() {
+	o.scopes = append(o.scopes, make(map[string]*substitution))
+}
+
+func (o *optimizer) endScope() {
+	o.scopes = o.scopes[:len(o.scopes)-1]
+}
+
+// declare adds a local variable to the innermost scope. constant is nil
+// unless the variable is bound to a constant.
+func (o *optimizer) declare(name Token, constant *LiteralExpr) *substitution {
+	sub := &substitution{constant: constant}
+	o.scopes[len(o.scopes)-1][name.Lexeme] = sub
+
+	return sub
+}
+
+func (o *optimizer) local(name string) *substitution {
+	for n := len(o.scopes) - 1; n >= 0; n-- {
+		if sub, ok := o.scopes[n][name]; ok {
+			return sub
+		}
+	}
+
+	return nil
+}
+
+// fold calls a pure builtin with constant arguments. ok is false if the
+// call can not be folded or fails, in which case it is left for runtime.
+func (o *optimizer) fold(name Token, arguments []Expression) (*LiteralExpr, bool) {
+	if o.loads || !pureBuiltins[name.Lexeme] || o.local(name.Lexeme) != nil || o.defined[name.Lexeme] || o.interpreter.globals.has(name.Lexeme) {
+		return nil, false
+	}
+
+	var args []interface{}
+
+	for _, arg := range arguments {
+		literal, ok := arg.(*LiteralExpr)
+		if !ok {
+			return nil, false
+		}
+
+		args = append(args, literal.Value)
+	}
+
+	builtin, err := o.interpreter.builtins.Get(name)
+	if err != nil {
+		return nil, false
+	}
+
+	ret, err := callFunction(name.Line, o.interpreter, builtin.(Function), args)
+	if err != nil {
+		return nil, false
+	}
+
+	switch ret.(type) {
+	case float64, bool, string, nil:
+		return &LiteralExpr{ret}, true
+	}
+
+	return nil, false
+}
+
+// pattern declares the variables of a pattern in the order they are bound
+// and optimizes the predicates of guards.
+func (o *optimizer) pattern(pattern Pattern) Pattern {
+	switch p := pattern.(type) {
+	case *BindingPattern:
+		o.declare(p.Name, nil)
+	case *ListPattern:
+		optimized := &ListPattern{Paren: p.Paren}
+
+		for _, el := range p.Elements {
+			optimized.Elements = append(optimized.Elements, o.pattern(el))
+		}
+
+		if p.Rest != nil {
+			optimized.Rest = o.pattern(p.Rest)
+		}
+
+		return optimized
+	case *GuardPattern:
+		predicate := o.optimize(p.Predicate)
+		return &GuardPattern{p.Paren, predicate, o.pattern(p.Pattern)}
+	}
+
+	return pattern
+}
+
+// function optimizes the body of a function with its parameters in scope.
+func (o *optimizer) function(params []Token, body Expression) Expression {
+	o.beginScope()
+	defer o.endScope()
+
+	for _, param := range params {
+		if param.TokenType != AmpRest {
+			o.declare(param, nil)
+		}
+	}
+
+	return o.optimize(body)
+}
+
+// bindings returns the bindings which can not be inlined.
+func bindings(names []Token, values []Expression, subs []*substitution) ([]Token, []Expression) {
+	var keptNames []Token
+	var keptValues []Expression
+
+	for n, sub := range subs {
+		if sub.constant == nil || sub.pinned {
+			keptNames = append(keptNames, names[n])
+			keptValues = append(keptValues, values[n])
+		}
+	}
+
+	return keptNames, keptValues
+}
+
+func (o *optimizer) visitLiteralExpr(literalExpr *LiteralExpr) (interface{}, error) {
+	return literalExpr, nil
+}
+
+func (o *optimizer) visitDefvarExpr(defvarExpr *DefvarExpr) (interface{}, error) {
+	return &DefvarExpr{defvarExpr.Name, o.optimize(defvarExpr.Initializer)}, nil
+}
+
+func (o *optimizer) visitDefparameterExpr(defparameterExpr *DefparameterExpr) (interface{}, error) {
+	return &DefparameterExpr{defparameterExpr.Name, o.optimize(defparameterExpr.Initializer)}, nil
+}
+
+func (o *optimizer) visitVarExpr(varExpr *VarExpr) (interface{}, error) {
+	if sub := o.local(varExpr.Name.Lexeme); sub != nil && sub.constant != nil {
+		return sub.constant, nil
+	}
+
+	return varExpr, nil
+}
+
+func (o *optimizer) visitLocalExpr(localExpr *LocalExpr) (interface{}, error) {
+	return localExpr, nil
+}
+
+func (o *optimizer) visitIfExpr(ifExpr *IfExpr) (interface{}, error) {
+	cond := o.optimize(ifExpr.Condition)
+
+	if literal, ok := cond.(*LiteralExpr); ok {
+		if isTruthy(literal.Value) {
+			return o.optimize(ifExpr.ThenBranch), nil
+		}
+
+		return o.optimize(ifExpr.ElseBranch), nil
+	}
+
+	return &IfExpr{cond, o.optimize(ifExpr.ThenBranch), o.optimize(ifExpr.ElseBranch)}, nil
+}
+
+func (o *optimizer) visitDefunExpr(defunExpr *DefunExpr) (interface{}, error) {
+	body := o.function(defunExpr.Params, defunExpr.Body)
+	return &DefunExpr{defunExpr.Name, defunExpr.Params, defunExpr.Doc, body}, nil
+}
+
+func (o *optimizer) visitFuncCallExpr(funcCallExpr *FuncCallExpr) (interface{}, error) {
+	if sub := o.local(funcCallExpr.Name.Lexeme); sub != nil {
+		sub.pinned = true
+	}
+
+	arguments := o.optimizeAll(funcCallExpr.Arguments)
+
+	if literal, ok := o.fold(funcCallExpr.Name, arguments); ok {
+		return literal, nil
+	}
+
+	return &FuncCallExpr{funcCallExpr.Name, arguments}, nil
+}
+
+func (o *optimizer) visitLocalCallExpr(localCallExpr *LocalCallExpr) (interface{}, error) {
+	arguments := o.optimizeAll(localCallExpr.Arguments)
+	return &LocalCallExpr{localCallExpr.Name, localCallExpr.Depth, localCallExpr.Slot, arguments}, nil
+}
+
+func (o *optimizer) visitListExpr(listExpr *ListExpr) (interface{}, error) {
+	return &ListExpr{o.optimizeAll(listExpr.Elements)}, nil
+}
+
+func (o *optimizer) visitLetExpr(letExpr *LetExpr) (interface{}, error) {
+	values := o.optimizeAll(letExpr.Values)
+
+	o.beginScope()
+	defer o.endScope()
+
+	var subs []*substitution
+
+	for n, name := range letExpr.Names {
+		constant, _ := values[n].(*LiteralExpr)
+		subs = append(subs, o.declare(name, constant))
+	}
+
+	body := o.optimize(letExpr.Body)
+
+	names, values := bindings(letExpr.Names, values, subs)
+	if len(names) == 0 {
+		return body, nil
+	}
+
+	return &LetExpr{names, values, body}, nil
+}
+
+func (o *optimizer) visitLetStarExpr(letStarExpr *LetStarExpr) (interface{}, error) {
+	o.beginScope()
+	defer o.endScope()
+
+	var values []Expression
+	var subs []*substitution
+
+	for n, name := range letStarExpr.Names {
+		value := o.optimize(letStarExpr.Values[n])
+		constant, _ := value.(*LiteralExpr)
+
+		values = append(values, value)
+		subs = append(subs, o.declare(name, constant))
+	}
+
+	body := o.optimize(letStarExpr.Body)
+
+	names, values := bindings(letStarExpr.Names, values, subs)
+	if len(names) == 0 {
+		return body, nil
+	}
+
+	return &LetStarExpr{names, values, body}, nil
+}
+
+func (o *optimizer) visitLetrecExpr(letrecExpr *LetrecExpr) (interface{}, error) {
+	o.beginScope()
+	defer o.endScope()
+
+	for _, name := range letrecExpr.Names {
+		o.declare(name, nil)
+	}
+
+	values := o.optimizeAll(letrecExpr.Values)
+	return &LetrecExpr{letrecExpr.Names, values, o.optimize(letrecExpr.Body)}, nil
+}
+
+func (o *optimizer) visitLambdaExpr(lambdaExpr *LambdaExpr) (interface{}, error) {
+	return &LambdaExpr{lambdaExpr.Params, o.function(lambdaExpr.Params, lambdaExpr.Body)}, nil
+}
+
+func (o *optimizer) visitDestructureExpr(destructureExpr *DestructureExpr) (interface{}, error) {
+	value := o.optimize(destructureExpr.Value)
+
+	o.beginScope()
+	defer o.endScope()
+
+	pattern := o.pattern(destructureExpr.Pattern)
+	return &DestructureExpr{pattern, value, o.optimize(destructureExpr.Body)}, nil
+}
+
+func (o *optimizer) visitModuleExpr(moduleExpr *ModuleExpr) (interface{}, error) {
+	return moduleExpr, nil
+}
+
+func (o *optimizer) visitRequireExpr(requireExpr *RequireExpr) (interface{}, error) {
+	return requireExpr, nil
+}
+
+func (o *optimizer) visitDefstructExpr(defstructExpr *DefstructExpr) (interface{}, error) {
+	return defstructExpr, nil
+}
+
+func (o *optimizer) visitMatchExpr(matchExpr *MatchExpr) (interface{}, error) {
+	value := o.optimize(matchExpr.Value)
+
+	var clauses []MatchClause
+
+	for _, clause := range matchExpr.Clauses {
+		o.beginScope()
+		pattern := o.pattern(clause.Pattern)
+		clauses = append(clauses, MatchClause{pattern, o.optimize(clause.Body)})
+		o.endScope()
+	}
+
+	return &MatchExpr{Keyword: matchExpr.Keyword, Value: value, Clauses: clauses}, nil
+}
+
+func (o *optimizer) visitSelectExpr(selectExpr *SelectExpr) (interface{}, error) {
+	var clauses []SelectClause
+
+	for _, clause := range selectExpr.Clauses {
+		optimized := clause
+
+		if clause.Channel != nil {
+			optimized.Channel = o.optimize(clause.Channel)
+		}
+
+		if clause.Value != nil {
+			optimized.Value = o.optimize(clause.Value)
+		}
+
+		o.beginScope()
+
+		if clause.Kind.Lexeme == "recv" {
+			o.declare(clause.Name, nil)
+		}
+
+		optimized.Body = o.optimize(clause.Body)
+		o.endScope()
+
+		clauses = append(clauses, optimized)
+	}
+
+	return &SelectExpr{selectExpr.Keyword, clauses}, nil
+}
+
+func (o *optimizer) visitParameterizeExpr(parameterizeExpr *ParameterizeExpr) (interface{}, error) {
+	for _, name := range parameterizeExpr.Names {
+		if sub := o.local(name.Lexeme); sub != nil {
+			sub.pinned = true
+		}
+	}
+
+	values := o.optimizeAll(parameterizeExpr.Values)
+	return &ParameterizeExpr{parameterizeExpr.Keyword, parameterizeExpr.Names, values, o.optimize(parameterizeExpr.Body)}, nil
+}
@@ -0,0 +1,144 @@
+package minimalisp_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	. "bakku.dev/minimalisp"
+)
+
+// optimize parses and optimizes a piece of source code.
+func optimize(t *testing.T, src string) []Expression {
+	t.Helper()
+	return NewInterpreter().Optimize(parse(t, src))
+}
+
+func expectLiteral(t *testing.T, src string, expected interface{}) {
+	t.Helper()
+
+	optimized := optimize(t, src)
+
+	literal, ok := optimized[len(optimized)-1].(*LiteralExpr)
+	if !ok {
+		t.Fatalf("Expected %s to be folded, got %#v", src, optimized[len(optimized)-1])
+	}
+
+	if literal.Value != expected {
+		t.Fatalf("Expected %s to be folded to %v, got %v", src, expected, literal.Value)
+	}
+}
+
+func expectNotFolded(t *testing.T, src string) {
+	t.Helper()
+
+	optimized := optimize(t, src)
+
+	if literal, ok := optimized[len(optimized)-1].(*LiteralExpr); ok {
+		t.Fatalf("Expected %s not to be folded, got %v", src, literal.Value)
+	}
+}
+
+func TestOptimizer_FoldsConstantCalls(t *testing.T) {
+	expectLiteral(t, "(+ 1 2)", 3.0)
+	expectLiteral(t, "(* (+ 1 2) (- 10 4))", 18.0)
+	expectLiteral(t, "(< 1 2)", true)
+	expectLiteral(t, `(= "a" "a")`, true)
+	expectLiteral(t, "(! (> 1 2))", true)
+	expectLiteral(t, "(and true (<= 1 1))", true)
+}
+
+func TestOptimizer_KeepsCallsWhichCanNotBeFolded(t *testing.T) {
+	expectNotFolded(t, "(defun f () 1) (+ 1 (f))")
+	expectNotFolded(t, "(+ 1 \"a\" '(1))")
+	expectNotFolded(t, "(len '(1 2))")
+	expectNotFolded(t, "(+ 1)")
+}
+
+func TestOptimizer_RespectsShadowedBuiltins(t *testing.T) {
+	expectNotFolded(t, "(defun + (a b) (- a b)) (+ 1 2)")
+	expectNotFolded(t, "(let (+ -) (+ 1 2))")
+	expectNotFolded(t, "(defun f (+) (+ 1 2)) (f *)")
+
+	interpreter := NewInterpreter()
+	if _, err := interpreter.Interpret(parse(t, "(defvar < >)")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, ok := interpreter.Optimize(parse(t, "(< 1 2)"))[0].(*LiteralExpr); ok {
+		t.Fatalf("Expected shadowed global not to be folded")
+	}
+}
+
+func TestOptimizer_EliminatesBranches(t *testing.T) {
+	optimized := optimize(t, "(if true a b)")
+	if v, ok := optimized[0].(*VarExpr); !ok || v.Name.Lexeme != "a" {
+		t.Fatalf("Expected the then branch, got %#v", optimized[0])
+	}
+
+	optimized = optimize(t, "(if (> 1 2) a b)")
+	if v, ok := optimized[0].(*VarExpr); !ok || v.Name.Lexeme != "b" {
+		t.Fatalf("Expected the else branch, got %#v", optimized[0])
+	}
+
+	if _, ok := optimize(t, "(defvar c true) (if c a b)")[1].(*IfExpr); !ok {
+		t.Fatalf("Expected the if to be kept")
+	}
+}
+
+func TestOptimizer_InlinesTrivialLets(t *testing.T) {
+	expectLiteral(t, "(let (x 1 y 2) (+ x y))", 3.0)
+	expectLiteral(t, "(let* (x 1 y (+ x 1)) (* x y))", 2.0)
+
+	optimized := optimize(t, "(defun f () 1) (let (x 1 y (f)) (+ x y))")
+	let, ok := optimized[1].(*LetExpr)
+	if !ok || len(let.Names) != 1 || let.Names[0].Lexeme != "y" {
+		t.Fatalf("Expected only y to be bound, got %#v", optimized[1])
+	}
+
+	// Variables which are called keep their binding.
+	if _, ok := optimize(t, "(let (x 1) (x))")[0].(*LetExpr); !ok {
+		t.Fatalf("Expected the let to be kept")
+	}
+}
+
+func TestOptimizer_PreservesSemantics(t *testing.T) {
+	programs := []string{
+		"(+ 1 2)",
+		"(if (= 1 1) \"yes\" \"no\")",
+		"(let (x 1) (let (x 2 y x) '(x y)))",
+		"(let* (a 1 b (+ a 1)) (let (a 10) '(a b)))",
+		"(defun f (x) (let (y 2) (* x y))) (f (+ 1 2))",
+		"(defun f (x) (if true x (undefined))) (f 1)",
+		"(let (x 1) (lambda () x))",
+		"(let (x 1) (match '(1 2) ((x y) (+ x y))))",
+		"(let (x 5) (match 6 ((? (lambda (v) (> v x)) v) v) (_ 0)))",
+		"(let (x 1) (x))",
+		"(defun + (a b) (- a b)) (+ 5 2)",
+		"(let (< >) (< 1 2))",
+		"(/ 1 0)",
+		"(defparameter *x* 1) (let (*x* 2) (parameterize ((*x* 3)) *x*))",
+	}
+
+	dir := writeFiles(t, map[string]string{"defs.mlisp": "(defun + (a b) 42)"})
+	defs := filepath.Join(dir, "defs.mlisp")
+	programs = append(programs,
+		fmt.Sprintf("(load %q) (+ 1 2)", defs),
+		fmt.Sprintf("(let (f load) (f %q)) (+ 1 2)", defs))
+
+	for _, src := range programs {
+		expected, expectedErr := NewInterpreter().Interpret(parse(t, src))
+		ret, err := NewInterpreter(WithOptimization()).Interpret(parse(t, src))
+
+		if fmt.Sprint(ret) != fmt.Sprint(expected) || fmt.Sprint(err) != fmt.Sprint(expectedErr) {
+			t.Fatalf("Expected %v and %v for %s, got %v and %v", expected, expectedErr, src, ret, err)
+		}
+	}
+}
+
+func TestOptimizer_ReportsStaticErrorsInEliminatedCode(t *testing.T) {
+	_, err := NewInterpreter(WithOptimization()).Interpret(parse(t, "(if true 1 (let (a 1 a 2) a))"))
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+}
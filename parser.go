@@ -7,6 +7,9 @@ type Parser struct {
 	tokens []Token
 	curr   int
 
+	// generated counts the parameters generated for destructuring patterns.
+	generated int
+
 	expressions []Expression
 }
 
@@ -35,9 +38,25 @@ func (p *Parser) declaration() (Expression, error) {
 			return p.varDef()
 		}
 
+		if p.matchN(Defparameter, 1) {
+			return p.parameterDef()
+		}
+
 		if p.matchN(Defun, 1) {
 			return p.funDef()
 		}
+
+		if p.matchN(Defstruct, 1) {
+			return p.structDef()
+		}
+
+		if p.matchN(ModuleDef, 1) {
+			return p.moduleDef()
+		}
+
+		if p.matchN(Require, 1) {
+			return p.require()
+		}
 	}
 
 	return p.expression()
@@ -69,6 +88,32 @@ func (p *Parser) varDef() (Expression, error) {
 	return &DefvarExpr{ident, expr}, nil
 }
 
+func (p *Parser) parameterDef() (Expression, error) {
+	if _, err := p.consume(LeftParen, "Expect '(' before parameter definition"); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(Defparameter, "Expect 'defparameter' after '('"); err != nil {
+		return nil, err
+	}
+
+	ident, err := p.consume(Identifier, "Expect identifier after 'defparameter'")
+	if err != nil {
+		return nil, err
+	}
+
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(RightParen, "Expect ')' after expression"); err != nil {
+		return nil, err
+	}
+
+	return &DefparameterExpr{ident, expr}, nil
+}
+
 func (p *Parser) funDef() (Expression, error) {
 	if _, err := p.consume(LeftParen, "Expect '(' before function definition"); err != nil {
 		return nil, err
@@ -87,31 +132,143 @@ func (p *Parser) funDef() (Expression, error) {
 		return nil, err
 	}
 
-	var params []Token
+	params, destructurings, err := p.parameters()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(RightParen, "Expect ')' after parameters"); err != nil {
+		return nil, err
+	}
+
+	// A string is only a docstring if the body follows it.
+	var doc string
+
+	if p.match(Str) && !p.matchN(RightParen, 1) {
+		doc = p.peek().Value.(string)
+		p.curr++
+	}
+
+	body, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(RightParen, "Expect ')' after body"); err != nil {
+		return nil, err
+	}
+
+	return &DefunExpr{ident, params, doc, destructure(body, destructurings)}, nil
+}
+
+func (p *Parser) structDef() (Expression, error) {
+	if _, err := p.consume(LeftParen, "Expect '(' before struct definition"); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(Defstruct, "Expect 'defstruct' after '('"); err != nil {
+		return nil, err
+	}
+
+	ident, err := p.consume(Identifier, "Expect identifier after 'defstruct'")
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []Token
 
 	for !p.match(RightParen) {
-		param, err := p.consume(Identifier, "Expect identifier as parameter")
+		field, err := p.consume(Identifier, "Expect identifier as field")
 		if err != nil {
 			return nil, err
 		}
 
-		params = append(params, param)
+		fields = append(fields, field)
 	}
 
-	if _, err := p.consume(RightParen, "Expect ')' after parameters"); err != nil {
+	if _, err := p.consume(RightParen, "Expect ')' after fields"); err != nil {
 		return nil, err
 	}
 
-	body, err := p.expression()
+	return &DefstructExpr{ident, fields}, nil
+}
+
+func (p *Parser) moduleDef() (Expression, error) {
+	if _, err := p.consume(LeftParen, "Expect '(' before module definition"); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(ModuleDef, "Expect 'module' after '('"); err != nil {
+		return nil, err
+	}
+
+	name, err := p.consume(Identifier, "Expect name of module after 'module'")
 	if err != nil {
 		return nil, err
 	}
 
-	if _, err := p.consume(RightParen, "Expect ')' after body"); err != nil {
+	if _, err := p.consume(LeftParen, "Expect '(' before export list"); err != nil {
 		return nil, err
 	}
 
-	return &DefunExpr{ident, params, body}, nil
+	if !p.match(Identifier) || p.peek().Lexeme != "export" {
+		return nil, p.error("Expect 'export' after '('")
+	}
+
+	p.curr++
+
+	exports := []Token{}
+
+	for !p.match(RightParen) {
+		export, err := p.consume(Identifier, "Expect identifier as export")
+		if err != nil {
+			return nil, err
+		}
+
+		exports = append(exports, export)
+	}
+
+	if _, err := p.consume(RightParen, "Expect ')' after export list"); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(RightParen, "Expect ')' after module definition"); err != nil {
+		return nil, err
+	}
+
+	return &ModuleExpr{name, exports}, nil
+}
+
+func (p *Parser) require() (Expression, error) {
+	if _, err := p.consume(LeftParen, "Expect '(' before require"); err != nil {
+		return nil, err
+	}
+
+	keyword, err := p.consume(Require, "Expect 'require' after '('")
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := p.consume(Str, "Expect path of module after 'require'")
+	if err != nil {
+		return nil, err
+	}
+
+	var alias Token
+
+	if p.match(Identifier) && p.peek().Lexeme == ":as" {
+		p.curr++
+
+		if alias, err = p.consume(Identifier, "Expect alias after ':as'"); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.consume(RightParen, "Expect ')' after require"); err != nil {
+		return nil, err
+	}
+
+	return &RequireExpr{keyword, path, alias}, nil
 }
 
 func (p *Parser) expression() (Expression, error) {
@@ -124,6 +281,26 @@ func (p *Parser) expression() (Expression, error) {
 			return p.letExpr()
 		}
 
+		if p.matchN(LetStar, 1) {
+			return p.letStarExpr()
+		}
+
+		if p.matchN(Letrec, 1) {
+			return p.letrecExpr()
+		}
+
+		if p.matchN(Match, 1) {
+			return p.matchExpr()
+		}
+
+		if p.matchN(Select, 1) {
+			return p.selectExpr()
+		}
+
+		if p.matchN(Parameterize, 1) {
+			return p.parameterizeExpr()
+		}
+
 		if p.matchN(Identifier, 1) {
 			return p.call()
 		}
@@ -164,50 +341,113 @@ func (p *Parser) ifExpr() (Expression, error) {
 }
 
 func (p *Parser) letExpr() (Expression, error) {
-	if _, err := p.consume(LeftParen, "Expect '(' before let expression"); err != nil {
+	names, values, destructurings, body, err := p.letForm(Let, "let", true)
+	if err != nil {
 		return nil, err
 	}
 
-	if _, err := p.consume(Let, "Expect 'let' after '('"); err != nil {
+	var bound []destructuring
+
+	for _, d := range destructurings {
+		if d != nil {
+			bound = append(bound, *d)
+		}
+	}
+
+	return &LetExpr{names, values, destructure(body, bound)}, nil
+}
+
+func (p *Parser) letStarExpr() (Expression, error) {
+	names, values, destructurings, body, err := p.letForm(LetStar, "let*", true)
+	if err != nil {
 		return nil, err
 	}
 
-	if _, err := p.consume(LeftParen, "Expect '(' before variable list"); err != nil {
+	// A destructured binding has to be bound before the following values are
+	// evaluated, so the bindings are split up after each pattern.
+	end := len(names)
+
+	for n := len(names) - 1; n >= 0; n-- {
+		if d := destructurings[n]; d != nil {
+			if n+1 < end {
+				body = &LetStarExpr{names[n+1 : end], values[n+1 : end], body}
+			}
+
+			body = &DestructureExpr{d.pattern, &VarExpr{d.name}, body}
+			end = n + 1
+		}
+	}
+
+	return &LetStarExpr{names[:end], values[:end], body}, nil
+}
+
+func (p *Parser) letrecExpr() (Expression, error) {
+	names, values, _, body, err := p.letForm(Letrec, "letrec", false)
+	if err != nil {
 		return nil, err
 	}
 
+	return &LetrecExpr{names, values, body}, nil
+}
+
+// letForm parses the parts which let, let* and letrec have in common. The
+// returned destructurings are aligned with the names and nil for plain names.
+func (p *Parser) letForm(keyword int, lexeme string, patterns bool) ([]Token, []Expression, []*destructuring, Expression, error) {
+	if _, err := p.consume(LeftParen, fmt.Sprintf("Expect '(' before %s expression", lexeme)); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	if _, err := p.consume(keyword, fmt.Sprintf("Expect '%s' after '('", lexeme)); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	if _, err := p.consume(LeftParen, "Expect '(' before variable list"); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
 	var names []Token
 	var values []Expression
+	var destructurings []*destructuring
 
 	for !p.match(RightParen) {
-		name, err := p.consume(Identifier, "Expect name of variable")
+		var name Token
+		var d *destructuring
+		var err error
+
+		if patterns {
+			name, d, err = p.target("Expect name of variable")
+		} else {
+			name, err = p.consume(Identifier, "Expect name of variable")
+		}
+
 		if err != nil {
-			return nil, err
+			return nil, nil, nil, nil, err
 		}
 
 		val, err := p.expression()
 		if err != nil {
-			return nil, err
+			return nil, nil, nil, nil, err
 		}
 
 		names = append(names, name)
 		values = append(values, val)
+		destructurings = append(destructurings, d)
 	}
 
 	if _, err := p.consume(RightParen, "Expect ')' after variable list"); err != nil {
-		return nil, err
+		return nil, nil, nil, nil, err
 	}
 
 	body, err := p.expression()
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, nil, err
 	}
 
-	if _, err := p.consume(RightParen, "Expect ')' after let body"); err != nil {
-		return nil, err
+	if _, err := p.consume(RightParen, fmt.Sprintf("Expect ')' after %s body", lexeme)); err != nil {
+		return nil, nil, nil, nil, err
 	}
 
-	return &LetExpr{names, values, body}, nil
+	return names, values, destructurings, body, nil
 }
 
 func (p *Parser) call() (Expression, error) {
@@ -263,7 +503,7 @@ func (p *Parser) primary() (Expression, error) {
 		return p.lambda()
 	}
 
-	return nil, &executionError{p.peek().Line, fmt.Sprintf("Expression expected.")}
+	return nil, p.error("Expression expected.")
 }
 
 func (p *Parser) list() (Expression, error) {
@@ -306,18 +546,287 @@ func (p *Parser) lambda() (Expression, error) {
 		return nil, err
 	}
 
+	params, destructurings, err := p.parameters()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(RightParen, "Expect ')' after parameter list"); err != nil {
+		return nil, err
+	}
+
+	body, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(RightParen, "Expect ')' after body"); err != nil {
+		return nil, err
+	}
+
+	return &LambdaExpr{params, destructure(body, destructurings)}, nil
+}
+
+// destructuring is a generated variable whose value is destructured
+// with a pattern before the body it belongs to is evaluated.
+type destructuring struct {
+	name    Token
+	pattern Pattern
+}
+
+// destructure wraps a body so that the destructurings are bound in order.
+func destructure(body Expression, destructurings []destructuring) Expression {
+	for n := len(destructurings) - 1; n >= 0; n-- {
+		d := destructurings[n]
+		body = &DestructureExpr{d.pattern, &VarExpr{d.name}, body}
+	}
+
+	return body
+}
+
+// parameters parses the parameters of a function up to the closing ')'.
+// A parameter may be a destructuring pattern and the last parameter may be
+// preceded by '&rest' to collect all remaining arguments into a list.
+func (p *Parser) parameters() ([]Token, []destructuring, error) {
 	var params []Token
+	var destructurings []destructuring
 
 	for !p.match(RightParen) {
-		param, err := p.consume(Identifier, "Expect identifier as parameter")
+		rest := p.match(AmpRest)
+		if rest {
+			params = append(params, p.peek())
+			p.curr++
+		}
+
+		param, d, err := p.target("Expect identifier or pattern as parameter")
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		params = append(params, param)
+
+		if d != nil {
+			destructurings = append(destructurings, *d)
+		}
+
+		if rest && !p.match(RightParen) {
+			return nil, nil, p.error("Expect ')' after rest parameter")
+		}
 	}
 
-	if _, err := p.consume(RightParen, "Expect ')' after parameter list"); err != nil {
+	return params, destructurings, nil
+}
+
+// target parses the name a value is bound to. If it is a pattern a variable
+// is generated which is destructured using the pattern.
+func (p *Parser) target(msg string) (Token, *destructuring, error) {
+	if !p.match(LeftParen) {
+		name, err := p.consume(Identifier, msg)
+		return name, nil, err
+	}
+
+	pattern, err := p.pattern(false)
+	if err != nil {
+		return Token{}, nil, err
+	}
+
+	name := Token{Identifier, fmt.Sprintf("#%d", p.generated), pattern.line(), nil}
+	p.generated++
+
+	return name, &destructuring{name, pattern}, nil
+}
+
+// pattern parses a destructuring pattern. Refutable patterns, which may
+// fail to match, are only allowed in match clauses.
+func (p *Parser) pattern(refutable bool) (Pattern, error) {
+	if p.match(Identifier) {
+		p.curr++
+
+		if p.peekN(-1).Lexeme == "_" {
+			return &WildcardPattern{p.peekN(-1)}, nil
+		}
+
+		return &BindingPattern{p.peekN(-1)}, nil
+	}
+
+	if refutable {
+		if literal, ok := p.literalPattern(); ok {
+			return literal, nil
+		}
+
+		if p.match(LeftParen) && p.matchN(Identifier, 1) && p.peekN(1).Lexeme == "?" {
+			return p.guardPattern()
+		}
+	}
+
+	paren, err := p.consume(LeftParen, "Expect identifier or '(' as pattern")
+	if err != nil {
+		return nil, err
+	}
+
+	pattern := &ListPattern{Paren: paren}
+
+	for !p.match(RightParen) {
+		if p.match(AmpRest) {
+			p.curr++
+
+			if pattern.Rest, err = p.pattern(refutable); err != nil {
+				return nil, err
+			}
+
+			break
+		}
+
+		el, err := p.pattern(refutable)
+		if err != nil {
+			return nil, err
+		}
+
+		pattern.Elements = append(pattern.Elements, el)
+	}
+
+	if _, err := p.consume(RightParen, "Expect ')' after pattern"); err != nil {
+		return nil, err
+	}
+
+	return pattern, nil
+}
+
+// literalPattern parses a number, string, boolean or nil as pattern.
+func (p *Parser) literalPattern() (Pattern, bool) {
+	token := p.peek()
+
+	switch token.TokenType {
+	case Number, Str:
+		p.curr++
+		return &LiteralPattern{token, token.Value}, true
+	case True:
+		p.curr++
+		return &LiteralPattern{token, true}, true
+	case False:
+		p.curr++
+		return &LiteralPattern{token, false}, true
+	case Nil:
+		p.curr++
+		return &LiteralPattern{token, nil}, true
+	}
+
+	return nil, false
+}
+
+// guardPattern parses (? predicate pattern).
+func (p *Parser) guardPattern() (Pattern, error) {
+	paren, err := p.consume(LeftParen, "Expect '(' before guard")
+	if err != nil {
+		return nil, err
+	}
+
+	p.curr++
+
+	predicate, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	pattern, err := p.pattern(true)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(RightParen, "Expect ')' after guard"); err != nil {
+		return nil, err
+	}
+
+	return &GuardPattern{paren, predicate, pattern}, nil
+}
+
+func (p *Parser) matchExpr() (Expression, error) {
+	if _, err := p.consume(LeftParen, "Expect '(' before match expression"); err != nil {
+		return nil, err
+	}
+
+	keyword, err := p.consume(Match, "Expect 'match' after '('")
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	var clauses []MatchClause
+
+	for !p.match(RightParen) {
+		if _, err := p.consume(LeftParen, "Expect '(' before match clause"); err != nil {
+			return nil, err
+		}
+
+		pattern, err := p.pattern(true)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := p.consume(RightParen, "Expect ')' after match clause"); err != nil {
+			return nil, err
+		}
+
+		clauses = append(clauses, MatchClause{pattern, body})
+	}
+
+	if _, err := p.consume(RightParen, "Expect ')' after match expression"); err != nil {
+		return nil, err
+	}
+
+	return &MatchExpr{Keyword: keyword, Value: value, Clauses: clauses}, nil
+}
+
+func (p *Parser) parameterizeExpr() (Expression, error) {
+	if _, err := p.consume(LeftParen, "Expect '(' before parameterize expression"); err != nil {
+		return nil, err
+	}
+
+	keyword, err := p.consume(Parameterize, "Expect 'parameterize' after '('")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(LeftParen, "Expect '(' before bindings"); err != nil {
+		return nil, err
+	}
+
+	var names []Token
+	var values []Expression
+
+	for !p.match(RightParen) {
+		if _, err := p.consume(LeftParen, "Expect '(' before binding"); err != nil {
+			return nil, err
+		}
+
+		name, err := p.consume(Identifier, "Expect identifier as dynamic variable")
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := p.consume(RightParen, "Expect ')' after binding"); err != nil {
+			return nil, err
+		}
+
+		names = append(names, name)
+		values = append(values, value)
+	}
+
+	if _, err := p.consume(RightParen, "Expect ')' after bindings"); err != nil {
 		return nil, err
 	}
 
@@ -326,11 +835,94 @@ func (p *Parser) lambda() (Expression, error) {
 		return nil, err
 	}
 
-	if _, err := p.consume(RightParen, "Expect ')' after body"); err != nil {
+	if _, err := p.consume(RightParen, "Expect ')' after parameterize expression"); err != nil {
+		return nil, err
+	}
+
+	return &ParameterizeExpr{keyword, names, values, body}, nil
+}
+
+func (p *Parser) selectExpr() (Expression, error) {
+	if _, err := p.consume(LeftParen, "Expect '(' before select expression"); err != nil {
+		return nil, err
+	}
+
+	keyword, err := p.consume(Select, "Expect 'select' after '('")
+	if err != nil {
+		return nil, err
+	}
+
+	var clauses []SelectClause
+	hasDefault := false
+
+	for !p.match(RightParen) {
+		clause, err := p.selectClause()
+		if err != nil {
+			return nil, err
+		}
+
+		if clause.Kind.Lexeme == "default" {
+			if hasDefault {
+				return nil, &executionError{clause.Kind.Line, "Select expression can only have one default clause"}
+			}
+
+			hasDefault = true
+		}
+
+		clauses = append(clauses, clause)
+	}
+
+	if _, err := p.consume(RightParen, "Expect ')' after select expression"); err != nil {
 		return nil, err
 	}
 
-	return &LambdaExpr{params, body}, nil
+	return &SelectExpr{keyword, clauses}, nil
+}
+
+// selectClause parses (recv ch name body), (send ch value body) or (default body).
+func (p *Parser) selectClause() (SelectClause, error) {
+	var clause SelectClause
+	var err error
+
+	if _, err = p.consume(LeftParen, "Expect '(' before select clause"); err != nil {
+		return clause, err
+	}
+
+	if clause.Kind, err = p.consume(Identifier, "Expect 'recv', 'send' or 'default' in select clause"); err != nil {
+		return clause, err
+	}
+
+	switch clause.Kind.Lexeme {
+	case "recv":
+		if clause.Channel, err = p.expression(); err != nil {
+			return clause, err
+		}
+
+		if clause.Name, err = p.consume(Identifier, "Expect name of received value"); err != nil {
+			return clause, err
+		}
+	case "send":
+		if clause.Channel, err = p.expression(); err != nil {
+			return clause, err
+		}
+
+		if clause.Value, err = p.expression(); err != nil {
+			return clause, err
+		}
+	case "default":
+	default:
+		return clause, &executionError{clause.Kind.Line, "Expect 'recv', 'send' or 'default' in select clause"}
+	}
+
+	if clause.Body, err = p.expression(); err != nil {
+		return clause, err
+	}
+
+	if _, err = p.consume(RightParen, "Expect ')' after select clause"); err != nil {
+		return clause, err
+	}
+
+	return clause, nil
 }
 
 func (p *Parser) peek() Token {
@@ -359,7 +951,7 @@ func (p *Parser) matchN(tokenType, n int) bool {
 
 func (p *Parser) consume(tokenType int, msg string) (Token, error) {
 	if !p.match(tokenType) {
-		return Token{}, &executionError{p.peek().Line, msg}
+		return Token{}, p.error(msg)
 	}
 
 	ret := p.peek()
@@ -368,6 +960,22 @@ func (p *Parser) consume(tokenType int, msg string) (Token, error) {
 	return ret, nil
 }
 
+// error returns an error at the current token. If only opening parens and
+// quotes are left, the tokens ended in the middle of an expression, which
+// more tokens could complete.
+func (p *Parser) error(msg string) error {
+	n := p.curr
+	for p.tokens[n].TokenType == LeftParen || p.tokens[n].TokenType == Quote {
+		n++
+	}
+
+	if p.tokens[n].TokenType == EOF {
+		return &IncompleteInputError{p.peek().Line, msg}
+	}
+
+	return &executionError{p.peek().Line, msg}
+}
+
 func (p *Parser) isAtEnd() bool {
 	return p.peek().TokenType == EOF
 }
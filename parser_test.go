@@ -1,6 +1,7 @@
 package minimalisp_test
 
 import (
+	"io/ioutil"
 	"testing"
 
 	. "bakku.dev/minimalisp"
@@ -219,3 +220,251 @@ func TestParse_ShouldReturnCorrectExpressionsForLambda(t *testing.T) {
 		t.Fatalf("Expected defvar expression")
 	}
 }
+
+func TestParse_ShouldDesugarDestructuringParameters(t *testing.T) {
+	tokens := []Token{
+		Token{LeftParen, "(", 1, nil},
+		Token{Lambda, "lambda", 1, nil},
+		Token{LeftParen, "(", 1, nil},
+		Token{LeftParen, "(", 1, nil},
+		Token{Identifier, "a", 1, nil},
+		Token{AmpRest, "&rest", 1, nil},
+		Token{Identifier, "b", 1, nil},
+		Token{RightParen, ")", 1, nil},
+		Token{RightParen, ")", 1, nil},
+		Token{Identifier, "b", 1, nil},
+		Token{RightParen, ")", 1, nil},
+		Token{EOF, "", 1, nil},
+	}
+
+	parser := NewParser(tokens)
+	expressions, err := parser.Parse()
+
+	if err != nil {
+		t.Fatalf("Expected err to be nil, got %v", err)
+	}
+
+	lambda, ok := expressions[0].(*LambdaExpr)
+	if !ok {
+		t.Fatalf("Expected lambda expression")
+	}
+
+	if len(lambda.Params) != 1 {
+		t.Fatalf("Expected %d params, got %d", 1, len(lambda.Params))
+	}
+
+	destructure, ok := lambda.Body.(*DestructureExpr)
+	if !ok {
+		t.Fatalf("Expected destructure expression as body")
+	}
+
+	if destructure.Pattern.String() != "(a &rest b)" {
+		t.Fatalf("Expected pattern '(a &rest b)', got '%s'", destructure.Pattern.String())
+	}
+}
+
+func TestParse_ShouldRejectParametersAfterRest(t *testing.T) {
+	tokens := []Token{
+		Token{LeftParen, "(", 1, nil},
+		Token{Lambda, "lambda", 1, nil},
+		Token{LeftParen, "(", 1, nil},
+		Token{AmpRest, "&rest", 1, nil},
+		Token{Identifier, "a", 1, nil},
+		Token{Identifier, "b", 1, nil},
+		Token{RightParen, ")", 1, nil},
+		Token{Identifier, "a", 1, nil},
+		Token{RightParen, ")", 1, nil},
+		Token{EOF, "", 1, nil},
+	}
+
+	parser := NewParser(tokens)
+	if _, err := parser.Parse(); err == nil {
+		t.Fatalf("Expected an error")
+	}
+}
+
+func TestParse_ShouldReturnCorrectExpressionsForLetStarAndLetrec(t *testing.T) {
+	for tokenType, lexeme := range map[int]string{LetStar: "let*", Letrec: "letrec"} {
+		tokens := []Token{
+			Token{LeftParen, "(", 1, nil},
+			Token{tokenType, lexeme, 1, nil},
+			Token{LeftParen, "(", 1, nil},
+			Token{Identifier, "n", 1, nil},
+			Token{Number, "1", 1, 1},
+			Token{RightParen, ")", 1, nil},
+			Token{Identifier, "n", 1, nil},
+			Token{RightParen, ")", 1, nil},
+			Token{EOF, "", 1, nil},
+		}
+
+		parser := NewParser(tokens)
+		expressions, err := parser.Parse()
+
+		if err != nil {
+			t.Fatalf("Expected err to be nil, got %v", err)
+		}
+
+		switch expressions[0].(type) {
+		case *LetStarExpr:
+			if tokenType != LetStar {
+				t.Fatalf("Expected letrec expression")
+			}
+		case *LetrecExpr:
+			if tokenType != Letrec {
+				t.Fatalf("Expected let* expression")
+			}
+		default:
+			t.Fatalf("Expected %s expression", lexeme)
+		}
+	}
+}
+
+func TestParse_ShouldReturnCorrectExpressionsForDefstructs(t *testing.T) {
+	tokens := []Token{
+		Token{LeftParen, "(", 1, nil},
+		Token{Defstruct, "defstruct", 1, nil},
+		Token{Identifier, "point", 1, nil},
+		Token{Identifier, "x", 1, nil},
+		Token{Identifier, "y", 1, nil},
+		Token{RightParen, ")", 1, nil},
+		Token{EOF, "", 1, nil},
+	}
+
+	parser := NewParser(tokens)
+	expressions, err := parser.Parse()
+
+	if err != nil {
+		t.Fatalf("Expected err to be nil, got %v", err)
+	}
+
+	defstruct, ok := expressions[0].(*DefstructExpr)
+	if !ok {
+		t.Fatalf("Expected defstruct expression")
+	}
+
+	if len(defstruct.Fields) != 2 {
+		t.Fatalf("Expected %d fields, got %d", 2, len(defstruct.Fields))
+	}
+}
+
+func TestParse_ShouldReturnCorrectExpressionsForMatch(t *testing.T) {
+	tokens := []Token{
+		Token{LeftParen, "(", 1, nil},
+		Token{Match, "match", 1, nil},
+		Token{Identifier, "n", 1, nil},
+		Token{LeftParen, "(", 1, nil},
+		Token{Number, "1", 1, 1.0},
+		Token{Str, "\"one\"", 1, "one"},
+		Token{RightParen, ")", 1, nil},
+		Token{LeftParen, "(", 2, nil},
+		Token{LeftParen, "(", 2, nil},
+		Token{Identifier, "?", 2, nil},
+		Token{Identifier, "pred", 2, nil},
+		Token{Identifier, "x", 2, nil},
+		Token{RightParen, ")", 2, nil},
+		Token{Identifier, "x", 2, nil},
+		Token{RightParen, ")", 2, nil},
+		Token{RightParen, ")", 2, nil},
+		Token{EOF, "", 2, nil},
+	}
+
+	parser := NewParser(tokens)
+	expressions, err := parser.Parse()
+
+	if err != nil {
+		t.Fatalf("Expected err to be nil, got %v", err)
+	}
+
+	matchExpr, ok := expressions[0].(*MatchExpr)
+	if !ok {
+		t.Fatalf("Expected match expression")
+	}
+
+	if len(matchExpr.Clauses) != 2 {
+		t.Fatalf("Expected %d clauses, got %d", 2, len(matchExpr.Clauses))
+	}
+
+	if _, ok := matchExpr.Clauses[0].Pattern.(*LiteralPattern); !ok {
+		t.Fatalf("Expected literal pattern, got %s", matchExpr.Clauses[0].Pattern)
+	}
+
+	if _, ok := matchExpr.Clauses[1].Pattern.(*GuardPattern); !ok {
+		t.Fatalf("Expected guard pattern, got %s", matchExpr.Clauses[1].Pattern)
+	}
+}
+
+func TestParse_ShouldRejectRefutablePatternsOutsideMatch(t *testing.T) {
+	tokens := []Token{
+		Token{LeftParen, "(", 1, nil},
+		Token{Let, "let", 1, nil},
+		Token{LeftParen, "(", 1, nil},
+		Token{LeftParen, "(", 1, nil},
+		Token{Number, "1", 1, 1.0},
+		Token{RightParen, ")", 1, nil},
+		Token{Identifier, "a", 1, nil},
+		Token{RightParen, ")", 1, nil},
+		Token{Nil, "nil", 1, nil},
+		Token{RightParen, ")", 1, nil},
+		Token{EOF, "", 1, nil},
+	}
+
+	parser := NewParser(tokens)
+	if _, err := parser.Parse(); err == nil {
+		t.Fatalf("Expected an error")
+	}
+}
+
+func TestParse_ShouldReturnCorrectExpressionsForParameterize(t *testing.T) {
+	tokens := []Token{
+		Token{LeftParen, "(", 1, nil},
+		Token{Parameterize, "parameterize", 1, nil},
+		Token{LeftParen, "(", 1, nil},
+		Token{LeftParen, "(", 1, nil},
+		Token{Identifier, "*x*", 1, nil},
+		Token{Number, "1", 1, 1.0},
+		Token{RightParen, ")", 1, nil},
+		Token{RightParen, ")", 1, nil},
+		Token{Identifier, "*x*", 1, nil},
+		Token{RightParen, ")", 1, nil},
+		Token{EOF, "", 1, nil},
+	}
+
+	parser := NewParser(tokens)
+	expressions, err := parser.Parse()
+
+	if err != nil {
+		t.Fatalf("Expected err to be nil, got %v", err)
+	}
+
+	parameterize, ok := expressions[0].(*ParameterizeExpr)
+	if !ok {
+		t.Fatalf("Expected parameterize expression")
+	}
+
+	if len(parameterize.Names) != 1 || parameterize.Names[0].Lexeme != "*x*" {
+		t.Fatalf("Expected binding of *x*, got %v", parameterize.Names)
+	}
+}
+
+func TestParse_ShouldReportIncompleteInput(t *testing.T) {
+	incomplete := []string{"(", "(+ 1", "(defun f (x)", "(let (a 1", "'(1 2", "(if true\n'"}
+	complete := []string{"(+ 1))", ")", "(defvar 1)"}
+
+	for _, src := range incomplete {
+		tokens, _ := NewScanner(src, ioutil.Discard).Scan()
+
+		_, err := NewParser(tokens).Parse()
+		if _, ok := err.(*IncompleteInputError); !ok {
+			t.Fatalf("Expected incomplete input for %q, got %v", src, err)
+		}
+	}
+
+	for _, src := range complete {
+		tokens, _ := NewScanner(src, ioutil.Discard).Scan()
+
+		_, err := NewParser(tokens).Parse()
+		if _, ok := err.(*IncompleteInputError); ok || err == nil {
+			t.Fatalf("Expected a syntax error for %q, got %v", src, err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package minimalisp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pattern describes the shape of a value which is destructured into variables.
+type Pattern interface {
+	bind(i *Interpreter, line int, value interface{}, env *Environment) error
+	line() int
+	String() string
+}
+
+// BindingPattern binds the whole value to a name.
+type BindingPattern struct {
+	Name Token
+}
+
+func (p *BindingPattern) bind(i *Interpreter, line int, value interface{}, env *Environment) error {
+	return env.Define(p.Name, value)
+}
+
+func (p *BindingPattern) line() int {
+	return p.Name.Line
+}
+
+func (p *BindingPattern) String() string {
+	return p.Name.Lexeme
+}
+
+// ListPattern destructures a list element by element. Rest is optional and
+// binds all remaining elements of the list.
+type ListPattern struct {
+	Paren    Token
+	Elements []Pattern
+	Rest     Pattern
+}
+
+func (p *ListPattern) bind(i *Interpreter, line int, value interface{}, env *Environment) error {
+	list, ok := value.(List)
+	if !ok {
+		return &executionError{line, fmt.Sprintf("Cannot destructure %v with pattern %s: expected a list", value, p)}
+	}
+
+	rest := list
+
+	for n, el := range p.Elements {
+		first, r, ok, err := next(i, rest)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return &executionError{line, fmt.Sprintf("Cannot destructure %v with pattern %s: expected %s elements but got %d", value, p, p.expected(), n)}
+		}
+
+		if err := el.bind(i, line, first, env); err != nil {
+			return err
+		}
+
+		rest = r
+	}
+
+	// The rest is bound as it is, so that lazy sequences stay unrealized.
+	if p.Rest != nil {
+		return p.Rest.bind(i, line, rest, env)
+	}
+
+	_, _, ok, err := next(i, rest)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		return &executionError{line, fmt.Sprintf("Cannot destructure %v with pattern %s: expected %s elements but got more", value, p, p.expected())}
+	}
+
+	return nil
+}
+
+func (p *ListPattern) line() int {
+	return p.Paren.Line
+}
+
+func (p *ListPattern) expected() string {
+	if p.Rest != nil {
+		return fmt.Sprintf("at least %d", len(p.Elements))
+	}
+
+	return fmt.Sprintf("%d", len(p.Elements))
+}
+
+func (p *ListPattern) String() string {
+	var elements []string
+
+	for _, el := range p.Elements {
+		elements = append(elements, el.String())
+	}
+
+	if p.Rest != nil {
+		elements = append(elements, "&rest", p.Rest.String())
+	}
+
+	return "(" + strings.Join(elements, " ") + ")"
+}
+
+// WildcardPattern matches any value without binding it.
+type WildcardPattern struct {
+	Token Token
+}
+
+func (p *WildcardPattern) bind(i *Interpreter, line int, value interface{}, env *Environment) error {
+	return nil
+}
+
+func (p *WildcardPattern) line() int {
+	return p.Token.Line
+}
+
+func (p *WildcardPattern) String() string {
+	return "_"
+}
+
+// LiteralPattern matches values equal to a literal. It can only be used in
+// match clauses.
+type LiteralPattern struct {
+	Token Token
+	Value interface{}
+}
+
+func (p *LiteralPattern) bind(i *Interpreter, line int, value interface{}, env *Environment) error {
+	if !equal(p.Value, value) {
+		return &executionError{line, fmt.Sprintf("Cannot destructure %v with pattern %s", value, p)}
+	}
+
+	return nil
+}
+
+func (p *LiteralPattern) line() int {
+	return p.Token.Line
+}
+
+func (p *LiteralPattern) String() string {
+	return p.Token.Lexeme
+}
+
+// GuardPattern matches values for which a predicate returns true and which
+// match the inner pattern. It can only be used in match clauses.
+type GuardPattern struct {
+	Paren     Token
+	Predicate Expression
+	Pattern   Pattern
+}
+
+func (p *GuardPattern) bind(i *Interpreter, line int, value interface{}, env *Environment) error {
+	return &executionError{line, fmt.Sprintf("Cannot destructure with guard pattern %s", p)}
+}
+
+func (p *GuardPattern) line() int {
+	return p.Paren.Line
+}
+
+func (p *GuardPattern) String() string {
+	return "(? ... " + p.Pattern.String() + ")"
+}
@@ -0,0 +1,258 @@
+package minimalisp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RecordType is a user-defined record type created by defstruct.
+type RecordType struct {
+	Name   string
+	Fields []Token
+}
+
+func (t *RecordType) String() string {
+	return "<struct " + t.Name + ">"
+}
+
+// Record is an instance of a user-defined record type.
+type Record struct {
+	recordType *RecordType
+	values     []interface{}
+}
+
+// Type returns the type of the record.
+func (r *Record) Type() *RecordType {
+	return r.recordType
+}
+
+// Get returns the value of a field or false if the record has no such field.
+func (r *Record) Get(field string) (interface{}, bool) {
+	for n, f := range r.recordType.Fields {
+		if f.Lexeme == field {
+			return r.values[n], true
+		}
+	}
+
+	return nil, false
+}
+
+// String prints the record as a call of its constructor.
+func (r *Record) String() string {
+	parts := []string{constructorName(r.recordType.Name)}
+
+	for _, val := range r.values {
+		parts = append(parts, readable(val))
+	}
+
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+// readable prints a value in a way that can be read back in.
+func readable(val interface{}) string {
+	switch v := val.(type) {
+	case string:
+		return strconv.Quote(v)
+	case nil:
+		return "nil"
+	case *ArrayList:
+		var elements []string
+
+		for _, el := range v.elements {
+			elements = append(elements, readable(el))
+		}
+
+		return "'(" + strings.Join(elements, " ") + ")"
+	}
+
+	return fmt.Sprintf("%v", val)
+}
+
+func (i *Interpreter) visitDefstructExpr(defstructExpr *DefstructExpr) (interface{}, error) {
+	name := defstructExpr.Name
+	recordType := &RecordType{name.Lexeme, defstructExpr.Fields}
+
+	names, functions := recordFunctions(recordType)
+
+	for n, fun := range functions {
+		if err := i.define(Token{Identifier, names[n], name.Line, nil}, fun); err != nil {
+			return nil, err
+		}
+	}
+
+	return recordType, nil
+}
+
+// recordFunctions returns the functions defstruct generates for a record
+// type and their names.
+func recordFunctions(t *RecordType) ([]string, []Function) {
+	names := []string{constructorName(t.Name), predicateName(t.Name)}
+	functions := []Function{&RecordConstructor{t}, &RecordPredicate{t}}
+
+	for n, field := range t.Fields {
+		names = append(names, accessorName(t.Name, field.Lexeme), updaterName(t.Name, field.Lexeme))
+		functions = append(functions, &RecordAccessor{t, n}, &RecordUpdater{t, n})
+	}
+
+	return names, functions
+}
+
+func constructorName(record string) string {
+	return "make-" + record
+}
+
+func predicateName(record string) string {
+	return record + "?"
+}
+
+func accessorName(record, field string) string {
+	return record + "-" + field
+}
+
+func updaterName(record, field string) string {
+	return record + "-with-" + field
+}
+
+// RecordConstructor creates a new record out of its field values.
+// Usage:
+// (defstruct point x y)
+// (make-point 1 2)
+type RecordConstructor struct {
+	recordType *RecordType
+}
+
+// Arity returns the amount of fields of the record type.
+func (f *RecordConstructor) Arity() int {
+	return len(f.recordType.Fields)
+}
+
+// Call creates the record.
+func (f *RecordConstructor) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	values := make([]interface{}, len(arguments))
+	copy(values, arguments)
+
+	return &Record{f.recordType, values}, nil
+}
+
+func (f *RecordConstructor) String() string {
+	return "<" + constructorName(f.recordType.Name) + ">"
+}
+
+// Doc documents the constructor.
+func (f *RecordConstructor) Doc() Documentation {
+	parts := []string{constructorName(f.recordType.Name)}
+
+	for _, field := range f.recordType.Fields {
+		parts = append(parts, field.Lexeme)
+	}
+
+	return Documentation{"(" + strings.Join(parts, " ") + ")", "Creates a " + f.recordType.Name + " record."}
+}
+
+// RecordPredicate checks whether a value is a record of a specific type.
+// Usage:
+// (point? (make-point 1 2)) => true
+type RecordPredicate struct {
+	recordType *RecordType
+}
+
+// Arity returns 1.
+func (f *RecordPredicate) Arity() int {
+	return 1
+}
+
+// Call implements the type check.
+func (f *RecordPredicate) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	record, ok := arguments[0].(*Record)
+	return ok && record.recordType == f.recordType, nil
+}
+
+func (f *RecordPredicate) String() string {
+	return "<" + predicateName(f.recordType.Name) + ">"
+}
+
+// Doc documents the predicate.
+func (f *RecordPredicate) Doc() Documentation {
+	return Documentation{"(" + predicateName(f.recordType.Name) + " value)", "Returns whether value is a " + f.recordType.Name + " record."}
+}
+
+// RecordAccessor returns the value of a field of a record.
+// Usage:
+// (point-x (make-point 1 2)) => 1
+type RecordAccessor struct {
+	recordType *RecordType
+	field      int
+}
+
+// Arity returns 1.
+func (f *RecordAccessor) Arity() int {
+	return 1
+}
+
+// Call implements the field access.
+func (f *RecordAccessor) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	record, err := expectRecord(line, f, f.recordType, arguments[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return record.values[f.field], nil
+}
+
+func (f *RecordAccessor) String() string {
+	return "<" + accessorName(f.recordType.Name, f.recordType.Fields[f.field].Lexeme) + ">"
+}
+
+// Doc documents the accessor.
+func (f *RecordAccessor) Doc() Documentation {
+	name, field := f.recordType.Name, f.recordType.Fields[f.field].Lexeme
+	return Documentation{"(" + accessorName(name, field) + " " + name + ")", "Returns the " + field + " field of a " + name + " record."}
+}
+
+// RecordUpdater returns a copy of a record with a different value for a field.
+// Usage:
+// (point-with-x (make-point 1 2) 5) => (make-point 5 2)
+type RecordUpdater struct {
+	recordType *RecordType
+	field      int
+}
+
+// Arity returns 2.
+func (f *RecordUpdater) Arity() int {
+	return 2
+}
+
+// Call implements the functional update.
+func (f *RecordUpdater) Call(line int, i *Interpreter, arguments []interface{}) (interface{}, error) {
+	record, err := expectRecord(line, f, f.recordType, arguments[0])
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(record.values))
+	copy(values, record.values)
+	values[f.field] = arguments[1]
+
+	return &Record{f.recordType, values}, nil
+}
+
+func (f *RecordUpdater) String() string {
+	return "<" + updaterName(f.recordType.Name, f.recordType.Fields[f.field].Lexeme) + ">"
+}
+
+// Doc documents the updater.
+func (f *RecordUpdater) Doc() Documentation {
+	name, field := f.recordType.Name, f.recordType.Fields[f.field].Lexeme
+	return Documentation{"(" + updaterName(name, field) + " " + name + " value)", "Returns a copy of a " + name + " record with a different " + field + "."}
+}
+
+// expectRecord makes sure that a value is a record of the given type.
+func expectRecord(line int, fun fmt.Stringer, recordType *RecordType, val interface{}) (*Record, error) {
+	record, ok := val.(*Record)
+	if !ok || record.recordType != recordType {
+		return nil, &executionError{line, fmt.Sprintf("%s expects a %s record but got %v", fun, recordType.Name, val)}
+	}
+
+	return record, nil
+}
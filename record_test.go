@@ -0,0 +1,49 @@
+package minimalisp_test
+
+import (
+	"strings"
+	"testing"
+)
+
+const point = "(defstruct point x y) (defstruct pair x y) "
+
+func TestRecord_Constructor(t *testing.T) {
+	expectResult(t, point+"(make-point 1 2)", "(make-point 1 2)")
+	expectResult(t, point+"(make-point \"a\" nil)", "(make-point \"a\" nil)")
+	expectResult(t, point+"(make-point '(1 2) (make-pair 3 4))", "(make-point '(1 2) (make-pair 3 4))")
+
+	expectError(t, point+"(make-point 1)")
+	expectError(t, point+"(make-point 1 2 3)")
+}
+
+func TestRecord_Predicate(t *testing.T) {
+	expectResult(t, point+"(point? (make-point 1 2))", "true")
+	expectResult(t, point+"(point? (make-pair 1 2))", "false")
+	expectResult(t, point+"(point? '(1 2))", "false")
+	expectResult(t, point+"(point? nil)", "false")
+}
+
+func TestRecord_Accessors(t *testing.T) {
+	expectResult(t, point+"(point-x (make-point 1 2))", "1")
+	expectResult(t, point+"(point-y (make-point 1 2))", "2")
+
+	err := expectError(t, point+"(point-x (make-pair 1 2))")
+	if !strings.Contains(err.Error(), "expects a point record") {
+		t.Fatalf("Expected wrong record error, got %v", err)
+	}
+
+	expectError(t, point+"(point-x 1)")
+}
+
+func TestRecord_Updaters(t *testing.T) {
+	expectResult(t, point+"(point-with-x (make-point 1 2) 3)", "(make-point 3 2)")
+	expectResult(t, point+"(defvar p (make-point 1 2)) (point-with-y p 3) p", "(make-point 1 2)")
+
+	expectError(t, point+"(point-with-x (make-pair 1 2) 3)")
+}
+
+func TestRecord_Equality(t *testing.T) {
+	expectResult(t, point+"(= (make-point 1 2) (make-point 1 2))", "true")
+	expectResult(t, point+"(= (make-point 1 2) (make-point 1 3))", "false")
+	expectResult(t, point+"(= (make-point 1 2) (make-pair 1 2))", "false")
+}
@@ -0,0 +1,594 @@
+package minimalisp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// resolver runs between parsing and interpretation. It computes where local
+// variables are found at runtime and reports errors which can be detected
+// without running the code. The resolved expressions are copies, so that
+// the same expressions can be interpreted by multiple goroutines.
+type resolver struct {
+	// globals contains the global variables which already exist.
+	globals *Environment
+	scopes  []*scope
+	// functions is the amount of functions enclosing the current expression.
+	functions int
+
+	// defined contains the globals defined so far by the resolved code and
+	// later those which are defined further down.
+	defined map[string]bool
+	later   map[string]bool
+}
+
+// scope mirrors an environment created at runtime.
+type scope struct {
+	bindings map[string]*binding
+	size     int
+}
+
+type binding struct {
+	slot int
+	// initialized is false while the values of a letrec are resolved.
+	initialized bool
+	functions   int
+}
+
+// resolve resolves top-level expressions against the current globals.
+func (i *Interpreter) resolve(expressions []Expression) ([]Expression, error) {
+	r := &resolver{
+		globals: i.globals,
+		defined: make(map[string]bool),
+		later:   make(map[string]bool),
+	}
+
+	for _, expr := range expressions {
+		for _, name := range definedNames(expr) {
+			r.later[name] = true
+		}
+	}
+
+	var resolved []Expression
+
+	for _, expr := range expressions {
+		res, err := r.resolve(expr)
+		if err != nil {
+			return nil, err
+		}
+
+		resolved = append(resolved, res)
+	}
+
+	return resolved, nil
+}
+
+// definedNames returns the names of the globals a top-level expression defines.
+func definedNames(expr Expression) []string {
+	switch e := expr.(type) {
+	case *DefvarExpr:
+		return []string{e.Name.Lexeme}
+	case *DefparameterExpr:
+		return []string{e.Name.Lexeme}
+	case *DefunExpr:
+		return []string{e.Name.Lexeme}
+	case *DefstructExpr:
+		names, _ := recordFunctions(&RecordType{e.Name.Lexeme, e.Fields})
+		return names
+	}
+
+	return nil
+}
+
+func (r *resolver) resolve(expr Expression) (Expression, error) {
+	ret, err := expr.Accept(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret.(Expression), nil
+}
+
+func (r *resolver) resolveAll(expressions []Expression) ([]Expression, error) {
+	var resolved []Expression
+
+	for _, expr := range expressions {
+		res, err := r.resolve(expr)
+		if err != nil {
+			return nil, err
+		}
+
+		resolved = append(resolved, res)
+	}
+
+	return resolved, nil
+}
+
+func (r *resolver) beginScope() {
+	r.scopes = append(r.scopes, &scope{bindings: make(map[string]*binding)})
+}
+
+func (r *resolver) endScope() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+// declare adds a variable to the innermost scope. kind describes the
+// variable in the error about duplicates.
+func (r *resolver) declare(name Token, kind string, initialized bool) error {
+	s := r.scopes[len(r.scopes)-1]
+
+	if _, ok := s.bindings[name.Lexeme]; ok {
+		return &StaticError{name.Line, fmt.Sprintf("Duplicate %s '%s'", kind, name.Lexeme)}
+	}
+
+	s.bindings[name.Lexeme] = &binding{s.size, initialized, r.functions}
+	s.size++
+
+	return nil
+}
+
+// lookup finds a local variable. ok is false for globals.
+func (r *resolver) lookup(name Token) (depth, slot int, ok bool, err error) {
+	for n := len(r.scopes) - 1; n >= 0; n-- {
+		b, ok := r.scopes[n].bindings[name.Lexeme]
+		if !ok {
+			continue
+		}
+
+		if !b.initialized && b.functions == r.functions {
+			return 0, 0, false, &StaticError{name.Line, fmt.Sprintf("Cannot read local variable '%s' before its definition", name.Lexeme)}
+		}
+
+		return len(r.scopes) - 1 - n, b.slot, true, nil
+	}
+
+	// Functions may refer to globals defined later, but top-level code runs
+	// in order.
+	if r.functions == 0 && r.later[name.Lexeme] && !r.defined[name.Lexeme] {
+		if _, err := r.globals.Get(name); err != nil {
+			return 0, 0, false, &StaticError{name.Line, fmt.Sprintf("Cannot use '%s' before its definition", name.Lexeme)}
+		}
+	}
+
+	return 0, 0, false, nil
+}
+
+// function resolves the parameters and the body of a function.
+func (r *resolver) function(params []Token, body Expression) (Expression, error) {
+	if err := checkParams(params, body); err != nil {
+		return nil, err
+	}
+
+	r.functions++
+	r.beginScope()
+
+	for _, param := range params {
+		if param.TokenType == AmpRest {
+			continue
+		}
+
+		if err := r.declare(param, "parameter", true); err != nil {
+			return nil, err
+		}
+	}
+
+	resolved, err := r.resolve(body)
+
+	r.endScope()
+	r.functions--
+
+	return resolved, err
+}
+
+// checkParams reports parameters with the same name, including the names
+// bound by destructured parameters.
+func checkParams(params []Token, body Expression) error {
+	var names []Token
+
+	for _, param := range params {
+		if param.TokenType != AmpRest && !strings.HasPrefix(param.Lexeme, "#") {
+			names = append(names, param)
+		}
+	}
+
+	for {
+		destructure, ok := body.(*DestructureExpr)
+		if !ok {
+			break
+		}
+
+		names = append(names, patternNames(destructure.Pattern)...)
+		body = destructure.Body
+	}
+
+	seen := make(map[string]bool)
+
+	for _, name := range names {
+		if seen[name.Lexeme] {
+			return &StaticError{name.Line, fmt.Sprintf("Duplicate parameter '%s'", name.Lexeme)}
+		}
+
+		seen[name.Lexeme] = true
+	}
+
+	return nil
+}
+
+// patternNames returns the names bound by a pattern.
+func patternNames(pattern Pattern) []Token {
+	switch p := pattern.(type) {
+	case *BindingPattern:
+		return []Token{p.Name}
+	case *ListPattern:
+		var names []Token
+
+		for _, el := range p.Elements {
+			names = append(names, patternNames(el)...)
+		}
+
+		if p.Rest != nil {
+			names = append(names, patternNames(p.Rest)...)
+		}
+
+		return names
+	case *GuardPattern:
+		return patternNames(p.Pattern)
+	}
+
+	return nil
+}
+
+// pattern declares the variables of a pattern in the order they are bound
+// and resolves the predicates of guards.
+func (r *resolver) pattern(pattern Pattern) (Pattern, error) {
+	switch p := pattern.(type) {
+	case *BindingPattern:
+		return p, r.declare(p.Name, "binding", true)
+	case *ListPattern:
+		resolved := &ListPattern{Paren: p.Paren}
+
+		for _, el := range p.Elements {
+			res, err := r.pattern(el)
+			if err != nil {
+				return nil, err
+			}
+
+			resolved.Elements = append(resolved.Elements, res)
+		}
+
+		if p.Rest != nil {
+			res, err := r.pattern(p.Rest)
+			if err != nil {
+				return nil, err
+			}
+
+			resolved.Rest = res
+		}
+
+		return resolved, nil
+	case *GuardPattern:
+		predicate, err := r.resolve(p.Predicate)
+		if err != nil {
+			return nil, err
+		}
+
+		inner, err := r.pattern(p.Pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		return &GuardPattern{p.Paren, predicate, inner}, nil
+	}
+
+	return pattern, nil
+}
+
+func (r *resolver) visitLiteralExpr(literalExpr *LiteralExpr) (interface{}, error) {
+	return literalExpr, nil
+}
+
+func (r *resolver) visitDefvarExpr(defvarExpr *DefvarExpr) (interface{}, error) {
+	initializer, err := r.resolve(defvarExpr.Initializer)
+	if err != nil {
+		return nil, err
+	}
+
+	r.defined[defvarExpr.Name.Lexeme] = true
+
+	return &DefvarExpr{defvarExpr.Name, initializer}, nil
+}
+
+func (r *resolver) visitDefparameterExpr(defparameterExpr *DefparameterExpr) (interface{}, error) {
+	initializer, err := r.resolve(defparameterExpr.Initializer)
+	if err != nil {
+		return nil, err
+	}
+
+	r.defined[defparameterExpr.Name.Lexeme] = true
+
+	return &DefparameterExpr{defparameterExpr.Name, initializer}, nil
+}
+
+func (r *resolver) visitVarExpr(varExpr *VarExpr) (interface{}, error) {
+	depth, slot, ok, err := r.lookup(varExpr.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		return &LocalExpr{varExpr.Name, depth, slot}, nil
+	}
+
+	return varExpr, nil
+}
+
+func (r *resolver) visitLocalExpr(localExpr *LocalExpr) (interface{}, error) {
+	return localExpr, nil
+}
+
+func (r *resolver) visitIfExpr(ifExpr *IfExpr) (interface{}, error) {
+	resolved, err := r.resolveAll([]Expression{ifExpr.Condition, ifExpr.ThenBranch, ifExpr.ElseBranch})
+	if err != nil {
+		return nil, err
+	}
+
+	return &IfExpr{resolved[0], resolved[1], resolved[2]}, nil
+}
+
+func (r *resolver) visitDefunExpr(defunExpr *DefunExpr) (interface{}, error) {
+	// The function is defined before its body runs, so it can call itself.
+	r.defined[defunExpr.Name.Lexeme] = true
+
+	body, err := r.function(defunExpr.Params, defunExpr.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DefunExpr{defunExpr.Name, defunExpr.Params, defunExpr.Doc, body}, nil
+}
+
+func (r *resolver) visitFuncCallExpr(funcCallExpr *FuncCallExpr) (interface{}, error) {
+	depth, slot, ok, err := r.lookup(funcCallExpr.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	arguments, err := r.resolveAll(funcCallExpr.Arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		return &LocalCallExpr{funcCallExpr.Name, depth, slot, arguments}, nil
+	}
+
+	return &FuncCallExpr{funcCallExpr.Name, arguments}, nil
+}
+
+func (r *resolver) visitLocalCallExpr(localCallExpr *LocalCallExpr) (interface{}, error) {
+	arguments, err := r.resolveAll(localCallExpr.Arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalCallExpr{localCallExpr.Name, localCallExpr.Depth, localCallExpr.Slot, arguments}, nil
+}
+
+func (r *resolver) visitListExpr(listExpr *ListExpr) (interface{}, error) {
+	elements, err := r.resolveAll(listExpr.Elements)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListExpr{elements}, nil
+}
+
+func (r *resolver) visitLetExpr(letExpr *LetExpr) (interface{}, error) {
+	values, err := r.resolveAll(letExpr.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	r.beginScope()
+	defer r.endScope()
+
+	for _, name := range letExpr.Names {
+		if err := r.declare(name, "binding", true); err != nil {
+			return nil, err
+		}
+	}
+
+	body, err := r.resolve(letExpr.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LetExpr{letExpr.Names, values, body}, nil
+}
+
+func (r *resolver) visitLetStarExpr(letStarExpr *LetStarExpr) (interface{}, error) {
+	var values []Expression
+
+	// Every binding has its own scope like in the interpreter.
+	for n, name := range letStarExpr.Names {
+		value, err := r.resolve(letStarExpr.Values[n])
+		if err != nil {
+			return nil, err
+		}
+
+		r.beginScope()
+		defer r.endScope()
+
+		if err := r.declare(name, "binding", true); err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	body, err := r.resolve(letStarExpr.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LetStarExpr{letStarExpr.Names, values, body}, nil
+}
+
+func (r *resolver) visitLetrecExpr(letrecExpr *LetrecExpr) (interface{}, error) {
+	r.beginScope()
+	defer r.endScope()
+
+	for _, name := range letrecExpr.Names {
+		if err := r.declare(name, "binding", false); err != nil {
+			return nil, err
+		}
+	}
+
+	var values []Expression
+
+	for n, name := range letrecExpr.Names {
+		value, err := r.resolve(letrecExpr.Values[n])
+		if err != nil {
+			return nil, err
+		}
+
+		r.scopes[len(r.scopes)-1].bindings[name.Lexeme].initialized = true
+		values = append(values, value)
+	}
+
+	body, err := r.resolve(letrecExpr.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LetrecExpr{letrecExpr.Names, values, body}, nil
+}
+
+func (r *resolver) visitLambdaExpr(lambdaExpr *LambdaExpr) (interface{}, error) {
+	body, err := r.function(lambdaExpr.Params, lambdaExpr.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LambdaExpr{lambdaExpr.Params, body}, nil
+}
+
+func (r *resolver) visitDestructureExpr(destructureExpr *DestructureExpr) (interface{}, error) {
+	value, err := r.resolve(destructureExpr.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	r.beginScope()
+	defer r.endScope()
+
+	pattern, err := r.pattern(destructureExpr.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := r.resolve(destructureExpr.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DestructureExpr{pattern, value, body}, nil
+}
+
+func (r *resolver) visitModuleExpr(moduleExpr *ModuleExpr) (interface{}, error) {
+	return moduleExpr, nil
+}
+
+func (r *resolver) visitRequireExpr(requireExpr *RequireExpr) (interface{}, error) {
+	return requireExpr, nil
+}
+
+func (r *resolver) visitDefstructExpr(defstructExpr *DefstructExpr) (interface{}, error) {
+	for _, name := range definedNames(defstructExpr) {
+		r.defined[name] = true
+	}
+
+	return defstructExpr, nil
+}
+
+func (r *resolver) visitMatchExpr(matchExpr *MatchExpr) (interface{}, error) {
+	value, err := r.resolve(matchExpr.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	var clauses []MatchClause
+
+	for _, clause := range matchExpr.Clauses {
+		r.beginScope()
+
+		pattern, err := r.pattern(clause.Pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := r.resolve(clause.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		r.endScope()
+
+		clauses = append(clauses, MatchClause{pattern, body})
+	}
+
+	return &MatchExpr{Keyword: matchExpr.Keyword, Value: value, Clauses: clauses}, nil
+}
+
+func (r *resolver) visitSelectExpr(selectExpr *SelectExpr) (interface{}, error) {
+	var clauses []SelectClause
+
+	for _, clause := range selectExpr.Clauses {
+		resolved := clause
+		var err error
+
+		if clause.Channel != nil {
+			if resolved.Channel, err = r.resolve(clause.Channel); err != nil {
+				return nil, err
+			}
+		}
+
+		if clause.Value != nil {
+			if resolved.Value, err = r.resolve(clause.Value); err != nil {
+				return nil, err
+			}
+		}
+
+		r.beginScope()
+
+		if clause.Kind.Lexeme == "recv" {
+			if err := r.declare(clause.Name, "binding", true); err != nil {
+				return nil, err
+			}
+		}
+
+		if resolved.Body, err = r.resolve(clause.Body); err != nil {
+			return nil, err
+		}
+
+		r.endScope()
+
+		clauses = append(clauses, resolved)
+	}
+
+	return &SelectExpr{selectExpr.Keyword, clauses}, nil
+}
+
+func (r *resolver) visitParameterizeExpr(parameterizeExpr *ParameterizeExpr) (interface{}, error) {
+	values, err := r.resolveAll(parameterizeExpr.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := r.resolve(parameterizeExpr.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ParameterizeExpr{parameterizeExpr.Keyword, parameterizeExpr.Names, values, body}, nil
+}
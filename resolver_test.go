@@ -0,0 +1,141 @@
+package minimalisp_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "bakku.dev/minimalisp"
+)
+
+func TestResolver_LocalVariables(t *testing.T) {
+	expectResult(t, "(defun f (a b) (let (c 3) (+ a b c))) (f 1 2)", "6")
+	expectResult(t, "(defun f (a &rest more) (cons a more)) (f 1 2 3)", "(1 2 3)")
+	expectResult(t, "(let (x 1) (let (x 2 y x) '(x y)))", "(2 1)")
+	expectResult(t, "(let* (a 1 b (+ a 1) c (* b 2)) '(a b c))", "(1 2 4)")
+	expectResult(t, "(defun add (a) (lambda (b) (lambda (c) (+ a b c)))) (let (add-one (add 1)) (apply (add-one 2) '(3)))", "6")
+	expectResult(t, "(defvar x 1) (defvar inner (let (x 2) (lambda () x))) (inner)", "2")
+	expectResult(t, "(let (f (lambda (x) (* x 2))) (f 21))", "42")
+	expectResult(t, "(defun f ((a b) &rest (c)) '(a b c)) (f '(1 2) 3)", "(1 2 3)")
+	expectResult(t, "(match '(1 2) ((a (? (lambda (x) (> x a)) b)) (+ a b)) (_ 0))", "3")
+	expectResult(t, "(let (c (chan 1)) (select (send c 5 (select (recv c v (* v 2))))))", "10")
+}
+
+func TestResolver_Letrec(t *testing.T) {
+	src := `
+	(letrec (even? (lambda (n) (if (= n 0) true (odd? (- n 1))))
+	         odd? (lambda (n) (if (= n 0) false (even? (- n 1)))))
+	  (even? 10))`
+
+	expectResult(t, src, "true")
+	expectResult(t, "(letrec (a 1 b (+ a 1)) b)", "2")
+
+	err := expectError(t, "(letrec (a b b 1) a)")
+	if !strings.Contains(err.Error(), "Cannot read local variable 'b' before its definition") {
+		t.Fatalf("Unexpected error %v", err)
+	}
+}
+
+func TestResolver_DuplicateParameters(t *testing.T) {
+	tests := []string{
+		"(defun f (a a) a)",
+		"(lambda (a b a) a)",
+		"(defun f (a &rest a) a)",
+		"(defun f ((a b) a) a)",
+		"(defun f ((a b) (c a)) a)",
+	}
+
+	for _, src := range tests {
+		err := expectError(t, src)
+		if !strings.Contains(err.Error(), "Duplicate parameter 'a'") {
+			t.Fatalf("Unexpected error %v for %s", err, src)
+		}
+	}
+}
+
+func TestResolver_DuplicateBindings(t *testing.T) {
+	tests := []string{
+		"(let (a 1 a 2) a)",
+		"(letrec (a 1 a 2) a)",
+		"(let ((a a) '(1 2)) a)",
+		"(match '(1 2) ((a a) a))",
+	}
+
+	for _, src := range tests {
+		err := expectError(t, src)
+		if !strings.Contains(err.Error(), "Duplicate binding 'a'") {
+			t.Fatalf("Unexpected error %v for %s", err, src)
+		}
+	}
+}
+
+func TestResolver_UseBeforeDefinition(t *testing.T) {
+	err := expectError(t, "(defvar y x) (defvar x 1)")
+	if !strings.Contains(err.Error(), "Cannot use 'x' before its definition") {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expectError(t, "(f) (defun f () 1)")
+	expectError(t, "(make-point 1 2) (defstruct point x y)")
+
+	// Functions may refer to globals which are defined later.
+	expectResult(t, "(defun f () (g)) (defun g () 1) (f)", "1")
+	expectResult(t, "(defun f (n) (if (= n 0) 0 (f (- n 1)))) (f 3)", "0")
+}
+
+func TestResolver_ReportsErrorsBeforeRunning(t *testing.T) {
+	var out bytes.Buffer
+
+	interpreter := NewInterpreter(WithOutput(&out))
+	if _, err := interpreter.Interpret(parse(t, `(println "running") (defun f (a a) a)`)); err == nil {
+		t.Fatalf("Expected an error")
+	}
+
+	if out.Len() != 0 {
+		t.Fatalf("Expected nothing to run, got %q", out.String())
+	}
+}
+
+func TestResolver_ExistingGlobals(t *testing.T) {
+	interpreter := NewInterpreter()
+
+	if _, err := interpreter.Interpret(parse(t, "(defvar x 1)")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ret, err := interpreter.Interpret(parse(t, "(defvar y x) y"))
+	if err != nil || ret != 1.0 {
+		t.Fatalf("Expected 1, got %v and %v", ret, err)
+	}
+}
+
+func TestResolver_SharedExpressions(t *testing.T) {
+	expressions := parse(t, "(defun f (a) (let (b 2) (* a b)))")
+	interpreter := NewInterpreter()
+
+	if _, err := interpreter.Interpret(expressions); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The resolver must not change the parsed expressions.
+	body := expressions[0].(*DefunExpr).Body.(*LetExpr).Body.(*FuncCallExpr)
+	if _, ok := body.Arguments[0].(*VarExpr); !ok {
+		t.Fatalf("Expected a var expression, got %T", body.Arguments[0])
+	}
+}
+
+func TestResolver_ReportsStaticErrors(t *testing.T) {
+	for _, src := range []string{
+		"(let (a 1 a 2) a)",
+		"(defun f (a a) a)",
+		"(defvar y x) (defvar x 1)",
+	} {
+		if _, ok := expectError(t, src).(*StaticError); !ok {
+			t.Fatalf("Expected a static error for %s", src)
+		}
+	}
+
+	if _, ok := expectError(t, "(/ 1 0)").(*StaticError); ok {
+		t.Fatalf("Expected a runtime error not to be static")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 // Scanner is responsible for scanning Minimalisp source code and returning
@@ -19,6 +20,11 @@ type Scanner struct {
 	src    string
 	tokens []Token
 
+	// comments makes the scanner return comments as Semicolon tokens.
+	comments bool
+	// incomplete is set if the source code ends in a string.
+	incomplete bool
+
 	// to specify where errors are written.
 	out io.Writer
 }
@@ -36,6 +42,20 @@ func NewScanner(src string, out io.Writer) *Scanner {
 func (s *Scanner) Scan() ([]Token, bool) {
 	var ok bool = true
 
+	// A shebang line makes scripts executable. It is kept as a comment.
+	if strings.HasPrefix(s.src, "#!") {
+		for s.end+1 < len(s.src) && s.peekN(1) != "\n" {
+			s.end++
+		}
+
+		if s.comments {
+			s.tokens = append(s.tokens, Token{Semicolon, s.src[s.start : s.end+1], s.line, nil})
+		}
+
+		s.end++
+		s.start = s.end
+	}
+
 	for !s.isAtEnd() {
 		err := s.nextToken()
 		if err != nil {
@@ -53,6 +73,20 @@ func (s *Scanner) Scan() ([]Token, bool) {
 	return s.tokens, ok
 }
 
+// ScanWithComments scans the source code like Scan but also returns the
+// comments, which tools like the formatter need to preserve them. The
+// tokens can not be parsed.
+func (s *Scanner) ScanWithComments() ([]Token, bool) {
+	s.comments = true
+	return s.Scan()
+}
+
+// Incomplete reports whether the source code ended in an unterminated
+// string, which more source code could complete.
+func (s *Scanner) Incomplete() bool {
+	return s.incomplete
+}
+
 func (s *Scanner) nextToken() error {
 	c := string(s.src[s.end])
 
@@ -64,12 +98,15 @@ func (s *Scanner) nextToken() error {
 		s.tokens = append(s.tokens, Token{RightParen, s.src[s.start : s.end+1], s.line, nil})
 		return nil
 	case ";":
-		s.end++
-
-		for s.peek() != "\n" && !s.isAtEnd() {
+		// The newline ending the comment is left for the next token.
+		for s.end+1 < len(s.src) && s.peekN(1) != "\n" {
 			s.end++
 		}
 
+		if s.comments {
+			s.tokens = append(s.tokens, Token{Semicolon, s.src[s.start : s.end+1], s.line, nil})
+		}
+
 		return nil
 	case "'":
 		s.tokens = append(s.tokens, Token{Quote, s.src[s.start : s.end+1], s.line, nil})
@@ -109,17 +146,23 @@ func (s *Scanner) nextToken() error {
 }
 
 func (s *Scanner) string() error {
+	line := s.line
 	s.end++
 
-	for s.peek() != "\"" && !s.isAtEnd() {
+	for !s.isAtEnd() && s.peek() != "\"" {
+		if s.peek() == "\n" {
+			s.line++
+		}
+
 		s.end++
 	}
 
 	if s.isAtEnd() {
-		return &executionError{s.line, "Unterminated string"}
+		s.incomplete = true
+		return &IncompleteInputError{line, "Unterminated string"}
 	}
 
-	s.tokens = append(s.tokens, Token{Str, s.src[s.start : s.end+1], s.line, s.src[s.start+1 : s.end]})
+	s.tokens = append(s.tokens, Token{Str, s.src[s.start : s.end+1], line, s.src[s.start+1 : s.end]})
 
 	return nil
 }
@@ -192,7 +235,7 @@ func isDigit(c string) bool {
 }
 
 func isAlpha(c string) bool {
-	matched, err := regexp.MatchString("[a-zA-Z\\+-<>!=/*%_?]", c)
+	matched, err := regexp.MatchString("[a-zA-Z\\+-<>!=/*%_?&]", c)
 	if err != nil {
 		return false
 	}
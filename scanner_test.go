@@ -63,3 +63,90 @@ func TestScanSourceCode_ShouldReturnCorrectSequenceOfTokens(t *testing.T) {
 		t.Fatalf("Expected token list size 45, got %v", len(tokens))
 	}
 }
+
+func TestScanSourceCode_ShouldKeepLinesAfterCommentsAndStrings(t *testing.T) {
+	sourceCode := "; comment\n(println \"two\nlines\")\nx"
+
+	var buf bytes.Buffer
+	tokens, ok := NewScanner(sourceCode, &buf).Scan()
+
+	if !ok {
+		t.Fatalf("Expected everything to be ok, got %s", buf.String())
+	}
+
+	last := tokens[len(tokens)-2]
+	if last.Lexeme != "x" || last.Line != 4 {
+		t.Fatalf("Expected x on line 4, got %s on line %v", last.Lexeme, last.Line)
+	}
+}
+
+func TestScanSourceCode_ShouldReturnCommentsOnlyInCommentMode(t *testing.T) {
+	sourceCode := "(a) ; trailing\n; own line\nb"
+
+	var buf bytes.Buffer
+	tokens, _ := NewScanner(sourceCode, &buf).Scan()
+
+	if len(tokens) != 5 {
+		t.Fatalf("Expected token list size 5, got %v", len(tokens))
+	}
+
+	tokens, _ = NewScanner(sourceCode, &buf).ScanWithComments()
+
+	if len(tokens) != 7 {
+		t.Fatalf("Expected token list size 7, got %v", len(tokens))
+	}
+
+	if tokens[3].TokenType != Semicolon || tokens[3].Lexeme != "; trailing" || tokens[3].Line != 1 {
+		t.Fatalf("Expected the trailing comment on line 1, got %v", tokens[3])
+	}
+
+	if tokens[4].Lexeme != "; own line" || tokens[4].Line != 2 {
+		t.Fatalf("Expected the second comment on line 2, got %v", tokens[4])
+	}
+}
+
+func TestScanSourceCode_ShouldReportUnterminatedStringsAsIncomplete(t *testing.T) {
+	var buf bytes.Buffer
+
+	scanner := NewScanner("(println \"hello", &buf)
+	if _, ok := scanner.Scan(); ok || !scanner.Incomplete() {
+		t.Fatalf("Expected the input to be incomplete")
+	}
+
+	scanner = NewScanner("(println \"hello\" #)", &buf)
+	if _, ok := scanner.Scan(); ok || scanner.Incomplete() {
+		t.Fatalf("Expected a scan error")
+	}
+}
+
+func TestTokenName_ShouldNameTokenTypes(t *testing.T) {
+	if name := TokenName(Identifier); name != "Identifier" {
+		t.Fatalf("Expected Identifier, got %s", name)
+	}
+
+	if name := TokenName(EOF); name != "EOF" {
+		t.Fatalf("Expected EOF, got %s", name)
+	}
+
+	if name := TokenName(-1); name != "Unknown(-1)" {
+		t.Fatalf("Expected Unknown(-1), got %s", name)
+	}
+}
+
+func TestScanSourceCode_ShouldSkipShebangLines(t *testing.T) {
+	var buf bytes.Buffer
+
+	tokens, ok := NewScanner("#!/usr/bin/env mlisp\n(f)", &buf).Scan()
+	if !ok {
+		t.Fatalf("Expected everything to be ok, got %s", buf.String())
+	}
+
+	if len(tokens) != 4 || tokens[0].TokenType != LeftParen || tokens[0].Line != 2 {
+		t.Fatalf("Expected the tokens of the second line, got %v", tokens)
+	}
+
+	tokens, _ = NewScanner("#!/usr/bin/env mlisp\n(f)", &buf).ScanWithComments()
+	if tokens[0].TokenType != Semicolon || tokens[0].Lexeme != "#!/usr/bin/env mlisp" {
+		t.Fatalf("Expected the shebang as a comment, got %v", tokens[0])
+	}
+}
@@ -1,8 +1,22 @@
 package minimalisp
 
+import "os"
+
+// newStdlibEnvironment creates an environment containing only the builtins.
+func newStdlibEnvironment() *Environment {
+	env := NewEnvironment()
+	setupStdlib(env)
+	return env
+}
+
 func setupStdlib(env *Environment) {
 	// IO
+	_ = env.Define(Token{Identifier, "*out*", -1, nil}, NewDynamicVar("*out*", NewOutput(os.Stdout)))
+	_ = env.Define(Token{Identifier, "*err*", -1, nil}, NewDynamicVar("*err*", NewOutput(os.Stderr)))
 	_ = env.Define(Token{Identifier, "println", -1, nil}, &Println{})
+	_ = env.Define(Token{Identifier, "load", -1, nil}, &Load{})
+	_ = env.Define(Token{Identifier, "doc", -1, nil}, &DocBuiltin{})
+	_ = env.Define(Token{Identifier, "exit", -1, nil}, &Exit{})
 
 	// Math
 	_ = env.Define(Token{Identifier, "+", -1, nil}, &Addition{})
@@ -16,7 +30,59 @@ func setupStdlib(env *Environment) {
 	_ = env.Define(Token{Identifier, "add", -1, nil}, &Add{})
 	_ = env.Define(Token{Identifier, "len", -1, nil}, &Len{})
 	_ = env.Define(Token{Identifier, "map", -1, nil}, &Map{})
+	_ = env.Define(Token{Identifier, "map-indexed", -1, nil}, &MapIndexed{})
 	_ = env.Define(Token{Identifier, "filter", -1, nil}, &Filter{})
+	_ = env.Define(Token{Identifier, "reduce", -1, nil}, &Reduce{})
+	_ = env.Define(Token{Identifier, "fold", -1, nil}, &Reduce{})
+	_ = env.Define(Token{Identifier, "range", -1, nil}, &Range{})
+	_ = env.Define(Token{Identifier, "nth", -1, nil}, &Nth{})
+	_ = env.Define(Token{Identifier, "last", -1, nil}, &Last{})
+	_ = env.Define(Token{Identifier, "take", -1, nil}, &Take{})
+	_ = env.Define(Token{Identifier, "drop", -1, nil}, &Drop{})
+	_ = env.Define(Token{Identifier, "reverse", -1, nil}, &Reverse{})
+	_ = env.Define(Token{Identifier, "concat", -1, nil}, &Concat{})
+	_ = env.Define(Token{Identifier, "sort", -1, nil}, &Sort{})
+	_ = env.Define(Token{Identifier, "zip", -1, nil}, &Zip{})
+	_ = env.Define(Token{Identifier, "any?", -1, nil}, &Any{})
+	_ = env.Define(Token{Identifier, "every?", -1, nil}, &Every{})
+	_ = env.Define(Token{Identifier, "find", -1, nil}, &Find{})
+	_ = env.Define(Token{Identifier, "index-of", -1, nil}, &IndexOf{})
+
+	// Lazy sequences
+	_ = env.Define(Token{Identifier, "lazy-seq", -1, nil}, &LazySeqBuiltin{})
+	_ = env.Define(Token{Identifier, "cons", -1, nil}, &ConsBuiltin{})
+	_ = env.Define(Token{Identifier, "iterate", -1, nil}, &Iterate{})
+	_ = env.Define(Token{Identifier, "repeat", -1, nil}, &Repeat{})
+	_ = env.Define(Token{Identifier, "cycle", -1, nil}, &Cycle{})
+
+	// Concurrency
+	_ = env.Define(Token{Identifier, "spawn", -1, nil}, &Spawn{})
+	_ = env.Define(Token{Identifier, "chan", -1, nil}, &Chan{})
+	_ = env.Define(Token{Identifier, "send", -1, nil}, &Send{})
+	_ = env.Define(Token{Identifier, "recv", -1, nil}, &Recv{})
+	_ = env.Define(Token{Identifier, "close", -1, nil}, &Close{})
+
+	// Atoms
+	_ = env.Define(Token{Identifier, "atom", -1, nil}, &AtomBuiltin{})
+	_ = env.Define(Token{Identifier, "deref", -1, nil}, &Deref{})
+	_ = env.Define(Token{Identifier, "reset!", -1, nil}, &Reset{})
+	_ = env.Define(Token{Identifier, "swap!", -1, nil}, &Swap{})
+	_ = env.Define(Token{Identifier, "add-watch", -1, nil}, &AddWatchBuiltin{})
+	_ = env.Define(Token{Identifier, "remove-watch", -1, nil}, &RemoveWatchBuiltin{})
+
+	// Futures
+	_ = env.Define(Token{Identifier, "future", -1, nil}, &FutureBuiltin{})
+	_ = env.Define(Token{Identifier, "promise", -1, nil}, &PromiseBuiltin{})
+	_ = env.Define(Token{Identifier, "deliver", -1, nil}, &Deliver{})
+	_ = env.Define(Token{Identifier, "pmap", -1, nil}, &PMap{})
+
+	// Functional
+	_ = env.Define(Token{Identifier, "apply", -1, nil}, &Apply{})
+	_ = env.Define(Token{Identifier, "partial", -1, nil}, &Partial{})
+	_ = env.Define(Token{Identifier, "comp", -1, nil}, &Comp{})
+	_ = env.Define(Token{Identifier, "identity", -1, nil}, &Identity{})
+	_ = env.Define(Token{Identifier, "constantly", -1, nil}, &Constantly{})
+	_ = env.Define(Token{Identifier, "memoize", -1, nil}, &Memoize{})
 
 	// Logical
 	_ = env.Define(Token{Identifier, "and", -1, nil}, &And{})
@@ -29,3 +95,91 @@ func setupStdlib(env *Environment) {
 	_ = env.Define(Token{Identifier, "!=", -1, nil}, &NotEq{})
 	_ = env.Define(Token{Identifier, "!", -1, nil}, &Not{})
 }
+
+// builtinDocs documents the builtins of the standard library.
+var builtinDocs = map[string]Documentation{
+	// IO
+	"*out*":   {"*out*", "The output stream println writes to. Defaults to standard output."},
+	"*err*":   {"*err*", "The standard error stream."},
+	"println": {"(println &rest values)", "Prints the values separated by spaces and a newline to *out*."},
+	"load":    {"(load path)", "Evaluates a source file in the current global environment."},
+	"doc":     {"(doc fn)", "Returns the docstring of a function or nil."},
+	"exit":    {"(exit code)", "Stops the script with an exit code between 0 and 255."},
+
+	// Math
+	"+": {"(+ a b &rest numbers)", "Adds numbers."},
+	"-": {"(- a b &rest numbers)", "Subtracts the remaining numbers from the first one."},
+	"*": {"(* a b &rest numbers)", "Multiplies numbers."},
+	"/": {"(/ a b &rest numbers)", "Divides the first number by the remaining ones."},
+
+	// Collection
+	"first":       {"(first list)", "Returns the first element of a list."},
+	"rest":        {"(rest list)", "Returns all except the first element of a list."},
+	"add":         {"(add list value)", "Returns a new list with value added at the end."},
+	"len":         {"(len list)", "Returns the amount of elements in a list."},
+	"map":         {"(map fn list &rest lists)", "Applies fn to the elements of the lists and returns the results. Stops at the end of the shortest list."},
+	"map-indexed": {"(map-indexed fn list)", "Applies fn to the index and the element of each element of a list."},
+	"filter":      {"(filter fn list)", "Returns the elements of a list for which fn returns a truthy value."},
+	"reduce":      {"(reduce fn [initial] list)", "Combines the elements of a list into a single value using fn."},
+	"fold":        {"(fold fn [initial] list)", "Combines the elements of a list into a single value using fn."},
+	"range":       {"(range [start] [end] [step])", "Returns a list of numbers from start up to but excluding end. Without arguments it returns an infinite lazy sequence starting at 0."},
+	"nth":         {"(nth list n)", "Returns the element at index n of a list."},
+	"last":        {"(last list)", "Returns the last element of a list."},
+	"take":        {"(take n list)", "Returns the first n elements of a list."},
+	"drop":        {"(drop n list)", "Returns all except the first n elements of a list."},
+	"reverse":     {"(reverse list)", "Returns a list with the elements in reverse order."},
+	"concat":      {"(concat &rest lists)", "Joins lists into a single list."},
+	"sort":        {"(sort [less] list)", "Returns a sorted list. less decides whether its first argument comes before its second one."},
+	"zip":         {"(zip &rest lists)", "Returns a list of lists where the nth list contains the nth element of each list."},
+	"any?":        {"(any? fn list)", "Returns whether fn returns a truthy value for any element of a list."},
+	"every?":      {"(every? fn list)", "Returns whether fn returns a truthy value for every element of a list."},
+	"find":        {"(find fn list)", "Returns the first element of a list for which fn returns a truthy value or nil."},
+	"index-of":    {"(index-of list value)", "Returns the index of value in a list or -1."},
+
+	// Lazy sequences
+	"lazy-seq": {"(lazy-seq fn)", "Returns a lazy sequence which calls fn without arguments when it is needed. fn returns a list or nil."},
+	"cons":     {"(cons value list)", "Prepends a value to a list without realizing it."},
+	"iterate":  {"(iterate fn x)", "Returns the infinite lazy sequence x, (fn x), (fn (fn x)) and so on."},
+	"repeat":   {"(repeat [n] value)", "Returns a lazy sequence which repeats a value n times or forever."},
+	"cycle":    {"(cycle list)", "Returns an infinite lazy sequence which repeats the elements of a list."},
+
+	// Concurrency
+	"spawn": {"(spawn fn &rest args)", "Calls fn with args on a new goroutine and returns a channel which receives the result."},
+	"chan":  {"(chan [size])", "Returns a channel buffering size values."},
+	"send":  {"(send ch value)", "Sends a value on a channel and returns it."},
+	"recv":  {"(recv ch)", "Receives a value from a channel. Returns nil once the channel is closed."},
+	"close": {"(close ch)", "Closes a channel."},
+
+	// Atoms
+	"atom":         {"(atom value)", "Returns an atom holding value."},
+	"deref":        {"(deref ref [timeout timeout-value])", "Returns the value of an atom, a future or a promise. Waits at most timeout milliseconds if given."},
+	"reset!":       {"(reset! atom value)", "Sets the value of an atom."},
+	"swap!":        {"(swap! atom fn &rest args)", "Sets the value of an atom to the result of applying fn to the current value and args."},
+	"add-watch":    {"(add-watch atom key fn)", "Calls fn with the key, the atom, the old and the new value after every change of the atom."},
+	"remove-watch": {"(remove-watch atom key)", "Removes a watch from an atom."},
+
+	// Futures
+	"future":  {"(future fn &rest args)", "Calls fn with args on a new goroutine. The result is obtained with deref."},
+	"promise": {"(promise)", "Returns a promise which receives its value with deliver."},
+	"deliver": {"(deliver promise value)", "Sets the value of a promise. Returns false if it was already delivered."},
+	"pmap":    {"(pmap fn list)", "Applies fn to the elements of a list in parallel and returns the results in order."},
+
+	// Functional
+	"apply":      {"(apply fn &rest args list)", "Calls fn with args followed by the elements of list."},
+	"partial":    {"(partial fn &rest args)", "Returns a function which calls fn with args followed by its own arguments."},
+	"comp":       {"(comp &rest fns)", "Returns the composition of functions, which are applied from right to left."},
+	"identity":   {"(identity value)", "Returns its argument."},
+	"constantly": {"(constantly value)", "Returns a function which always returns value."},
+	"memoize":    {"(memoize fn)", "Returns a function which caches the results of fn."},
+
+	// Logical
+	"and": {"(and a b &rest values)", "Returns the last value if all values are truthy, otherwise false."},
+	"or":  {"(or a b &rest values)", "Returns the first truthy value or false."},
+	"<":   {"(< a b &rest values)", "Returns whether the values are strictly increasing."},
+	"<=":  {"(<= a b &rest values)", "Returns whether the values are increasing."},
+	">":   {"(> a b &rest values)", "Returns whether the values are strictly decreasing."},
+	">=":  {"(>= a b &rest values)", "Returns whether the values are decreasing."},
+	"=":   {"(= a b &rest values)", "Returns whether all values are equal."},
+	"!=":  {"(!= a b &rest values)", "Returns whether no two adjacent values are equal."},
+	"!":   {"(! value)", "Returns whether value is falsy."},
+}
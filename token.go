@@ -1,5 +1,7 @@
 package minimalisp
 
+import "fmt"
+
 const (
 	LeftParen = iota
 	RightParen
@@ -12,22 +14,81 @@ const (
 	True
 	False
 	Defvar
+	Defparameter
 	Defun
+	Defstruct
 	If
 	Let
+	LetStar
+	Letrec
+	Match
+	Select
+	Parameterize
 	Nil
+	ModuleDef
+	Require
+	AmpRest
 	EOF
 )
 
+var tokenNames = map[int]string{
+	LeftParen:    "LeftParen",
+	RightParen:   "RightParen",
+	Semicolon:    "Semicolon",
+	Quote:        "Quote",
+	Identifier:   "Identifier",
+	Str:          "Str",
+	Number:       "Number",
+	Lambda:       "Lambda",
+	True:         "True",
+	False:        "False",
+	Defvar:       "Defvar",
+	Defparameter: "Defparameter",
+	Defun:        "Defun",
+	Defstruct:    "Defstruct",
+	If:           "If",
+	Let:          "Let",
+	LetStar:      "LetStar",
+	Letrec:       "Letrec",
+	Match:        "Match",
+	Select:       "Select",
+	Parameterize: "Parameterize",
+	Nil:          "Nil",
+	ModuleDef:    "ModuleDef",
+	Require:      "Require",
+	AmpRest:      "AmpRest",
+	EOF:          "EOF",
+}
+
+// TokenName returns the name of a token type like "Identifier".
+func TokenName(tokenType int) string {
+	if name, ok := tokenNames[tokenType]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Unknown(%d)", tokenType)
+}
+
 var keywords = map[string]int{
-	"lambda": Lambda,
-	"true":   True,
-	"false":  False,
-	"let":    Let,
-	"defvar": Defvar,
-	"defun":  Defun,
-	"if":     If,
-	"nil":    Nil,
+	"lambda":       Lambda,
+	"true":         True,
+	"false":        False,
+	"let":          Let,
+	"let*":         LetStar,
+	"letrec":       Letrec,
+	"match":        Match,
+	"select":       Select,
+	"defvar":       Defvar,
+	"defparameter": Defparameter,
+	"parameterize": Parameterize,
+	"defun":        Defun,
+	"defstruct":    Defstruct,
+	"defrecord":    Defstruct,
+	"if":           If,
+	"nil":          Nil,
+	"module":       ModuleDef,
+	"require":      Require,
+	"&rest":        AmpRest,
 }
 
 // Token represents a certain token at a specific location
@@ -0,0 +1,496 @@
+package minimalisp
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Backend selects how an Interpreter executes code.
+type Backend int
+
+const (
+	// TreeWalker evaluates the expressions directly.
+	TreeWalker Backend = iota
+	// Bytecode compiles the expressions to bytecode which is executed by a
+	// stack based virtual machine.
+	Bytecode
+)
+
+func (b Backend) String() string {
+	if b == Bytecode {
+		return "bytecode"
+	}
+
+	return "tree-walker"
+}
+
+// WithBackend selects the backend which executes code. The tree-walking
+// interpreter is used by default.
+func WithBackend(backend Backend) Option {
+	return func(i *Interpreter) {
+		i.backend = backend
+	}
+}
+
+// frame holds the local variables of a call of a compiled function.
+type frame struct {
+	slots  []interface{}
+	parent *frame
+}
+
+// compiledFunction is a function which is executed by the virtual machine.
+type compiledFunction struct {
+	proto *prototype
+	frame *frame
+	// globals is the global environment the function was defined in.
+	globals *Environment
+}
+
+// Arity returns the amount of params which are expected for a function call.
+func (f *compiledFunction) Arity() int {
+	if f.proto.rest >= 0 {
+		return infiniteArity
+	}
+
+	return len(f.proto.params)
+}
+
+// Call calls the function.
+func (f *compiledFunction) Call(line int, interpreter *Interpreter, args []interface{}) (interface{}, error) {
+	fr, err := f.newFrame(line, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return interpreter.run(f.proto, fr, f.globals)
+}
+
+// newFrame creates the frame of a call and copies the arguments into it.
+func (f *compiledFunction) newFrame(line int, args []interface{}) (*frame, error) {
+	fr := &frame{make([]interface{}, f.proto.slots), f.frame}
+	rest := f.proto.rest
+
+	if rest < 0 {
+		copy(fr.slots, args)
+		return fr, nil
+	}
+
+	if len(args) < rest {
+		return nil, &executionError{line, fmt.Sprintf("Expected at least %d arguments but got %d", rest, len(args))}
+	}
+
+	copy(fr.slots, args[:rest])
+	fr.slots[rest] = NewArrayList(append([]interface{}(nil), args[rest:]...))
+
+	return fr, nil
+}
+
+// Doc returns the signature and the docstring of the function.
+func (f *compiledFunction) Doc() Documentation {
+	return Documentation{signature(f.proto.name, f.proto.params, f.proto.body), f.proto.doc}
+}
+
+func (f *compiledFunction) String() string {
+	return "<" + f.proto.name + ">"
+}
+
+// evaluate evaluates resolved expressions in the current environment with
+// the selected backend.
+func (i *Interpreter) evaluate(expressions []Expression) (interface{}, error) {
+	if i.backend == Bytecode {
+		script, err := compileScript(expressions)
+		if err != nil {
+			return nil, err
+		}
+
+		return i.run(script, &frame{slots: make([]interface{}, script.slots)}, i.current)
+	}
+
+	var ret interface{}
+	var err error
+
+	for _, expr := range expressions {
+		if ret, err = expr.Accept(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return ret, nil
+}
+
+// run executes compiled code. The values of the virtual machine are stored
+// on the stack of the interpreter, which is shared by nested calls.
+func (i *Interpreter) run(proto *prototype, fr *frame, globals *Environment) (interface{}, error) {
+	prevEnv, prevDynamic, base := i.current, i.dynamic, len(i.stack)
+	i.current = globals
+
+	ret, err := i.loop(proto, fr)
+
+	i.current = prevEnv
+
+	if err != nil {
+		i.truncate(base)
+		i.dynamic = prevDynamic
+	}
+
+	return ret, err
+}
+
+func (i *Interpreter) push(value interface{}) {
+	i.stack = append(i.stack, value)
+}
+
+func (i *Interpreter) pop() interface{} {
+	top := len(i.stack) - 1
+	value := i.stack[top]
+	i.stack[top] = nil
+	i.stack = i.stack[:top]
+
+	return value
+}
+
+func (i *Interpreter) peek(distance int) interface{} {
+	return i.stack[len(i.stack)-1-distance]
+}
+
+// truncate drops all values above height from the stack.
+func (i *Interpreter) truncate(height int) {
+	for n := height; n < len(i.stack); n++ {
+		i.stack[n] = nil
+	}
+
+	i.stack = i.stack[:height]
+}
+
+func (i *Interpreter) loop(proto *prototype, fr *frame) (interface{}, error) {
+	code, constants := proto.code, proto.constants
+	ip := 0
+
+	// marks are the stack heights a failed match clause resets to.
+	var marks []int
+	// dynamic are the bindings which opUnparameterize restores.
+	var dynamic []*dynamicBinding
+
+	read := func() int {
+		operand := int(code[ip])<<8 | int(code[ip+1])
+		ip += 2
+		return operand
+	}
+
+	for {
+		line := proto.lines[ip]
+		op := opcode(code[ip])
+		ip++
+
+		switch op {
+		case opConstant:
+			i.push(constants[read()])
+		case opNil:
+			i.push(nil)
+		case opPop:
+			i.pop()
+		case opDup:
+			i.push(i.peek(0))
+		case opGetLocal:
+			depth, slot := read(), read()
+			env := fr
+
+			for ; depth > 0; depth-- {
+				env = env.parent
+			}
+
+			i.push(env.slots[slot])
+		case opSetLocal:
+			fr.slots[read()] = i.pop()
+		case opGetGlobal:
+			val, err := i.lookup(constants[read()].(Token))
+			if err != nil {
+				return nil, err
+			}
+
+			i.push(val)
+		case opDefine:
+			name := constants[read()].(Token)
+
+			if err := i.define(name, i.peek(0)); err != nil {
+				return nil, err
+			}
+		case opDefineDynamic:
+			name := constants[read()].(Token)
+
+			if err := i.define(name, NewDynamicVar(name.Lexeme, i.peek(0))); err != nil {
+				return nil, err
+			}
+		case opJump:
+			ip = read()
+		case opJumpIfFalse:
+			target := read()
+
+			if !isTruthy(i.pop()) {
+				ip = target
+			}
+		case opCallable:
+			name := constants[read()].(Token)
+
+			if _, ok := i.peek(0).(Function); !ok {
+				return nil, &executionError{name.Line, fmt.Sprintf("%s is not a function", name.Lexeme)}
+			}
+		case opCall:
+			ret, err := i.callValue(line, read())
+			if err != nil {
+				return nil, err
+			}
+
+			i.push(ret)
+		case opList:
+			n := read()
+			elements := append([]interface{}(nil), i.stack[len(i.stack)-n:]...)
+			i.truncate(len(i.stack) - n)
+			i.push(NewArrayList(elements))
+		case opClosure:
+			i.push(&compiledFunction{constants[read()].(*prototype), fr, i.current})
+		case opEval:
+			val, err := constants[read()].(Expression).Accept(i)
+			if err != nil {
+				return nil, err
+			}
+
+			i.push(val)
+		case opListBegin:
+			pattern, fail := constants[read()].(*ListPattern), read()
+			value := i.pop()
+
+			list, ok := value.(List)
+			if !ok {
+				if fail == noJump {
+					return nil, &executionError{line, fmt.Sprintf("Cannot destructure %v with pattern %s: expected a list", value, pattern)}
+				}
+
+				ip = fail
+				continue
+			}
+
+			i.push(value)
+			i.push(list)
+		case opListNext:
+			pattern, n, fail := constants[read()].(*ListPattern), read(), read()
+
+			first, rest, ok, err := next(i, i.peek(0).(List))
+			if err != nil {
+				return nil, err
+			}
+
+			if !ok {
+				if fail == noJump {
+					return nil, &executionError{line, fmt.Sprintf("Cannot destructure %v with pattern %s: expected %s elements but got %d", i.peek(1), pattern, pattern.expected(), n)}
+				}
+
+				ip = fail
+				continue
+			}
+
+			i.stack[len(i.stack)-1] = rest
+			i.push(first)
+		case opListRest:
+			remaining := i.pop().(List)
+			i.pop()
+
+			i.push(remaining)
+		case opListEnd:
+			pattern, fail := constants[read()].(*ListPattern), read()
+			remaining := i.pop().(List)
+			value := i.pop()
+
+			_, _, ok, err := next(i, remaining)
+			if err != nil {
+				return nil, err
+			}
+
+			if ok {
+				if fail == noJump {
+					return nil, &executionError{line, fmt.Sprintf("Cannot destructure %v with pattern %s: expected %s elements but got more", value, pattern, pattern.expected())}
+				}
+
+				ip = fail
+			}
+		case opMatchLiteral:
+			pattern, fail := constants[read()].(*LiteralPattern), read()
+			value := i.pop()
+
+			if !equal(pattern.Value, value) {
+				if fail == noJump {
+					return nil, &executionError{line, fmt.Sprintf("Cannot destructure %v with pattern %s", value, pattern)}
+				}
+
+				ip = fail
+			}
+		case opGuard:
+			pattern, fail := constants[read()].(*GuardPattern), read()
+			pred := i.pop()
+
+			fun, ok := pred.(Function)
+			if !ok {
+				return nil, &executionError{pattern.Paren.Line, fmt.Sprintf("Guard %v is not a function", pred)}
+			}
+
+			ret, err := callFunction(pattern.Paren.Line, i, fun, []interface{}{i.peek(0)})
+			if err != nil {
+				return nil, err
+			}
+
+			if !isTruthy(ret) {
+				ip = fail
+			}
+		case opMark:
+			marks = append(marks, len(i.stack))
+		case opUnmark:
+			marks = marks[:len(marks)-1]
+		case opRestore:
+			i.truncate(marks[len(marks)-1])
+			marks = marks[:len(marks)-1]
+		case opNoMatch:
+			return nil, &executionError{line, fmt.Sprintf("No matching clause for %v", i.pop())}
+		case opCheckChannel:
+			kind := constants[read()].(Token)
+
+			if _, ok := i.peek(0).(*Channel); !ok {
+				return nil, &executionError{kind.Line, fmt.Sprintf("Cannot %s with %v: expected a channel", kind.Lexeme, i.peek(0))}
+			}
+		case opSelect:
+			table := constants[read()].(*selectTable)
+
+			target, err := i.selectClause(line, table)
+			if err != nil {
+				return nil, err
+			}
+
+			ip = target
+		case opGetDynamic:
+			name := constants[read()].(Token)
+
+			raw, err := i.lookupVar(name)
+			if err != nil {
+				return nil, err
+			}
+
+			if _, ok := raw.(*DynamicVar); !ok {
+				return nil, &executionError{name.Line, fmt.Sprintf("'%s' is not a dynamic variable", name.Lexeme)}
+			}
+
+			i.push(raw)
+		case opParameterize:
+			n := read()
+			start := len(i.stack) - 2*n
+			bindings := i.dynamic
+
+			for k := start; k < len(i.stack); k += 2 {
+				bindings = &dynamicBinding{i.stack[k].(*DynamicVar), i.stack[k+1], bindings}
+			}
+
+			i.truncate(start)
+			dynamic = append(dynamic, i.dynamic)
+			i.dynamic = bindings
+		case opUnparameterize:
+			i.dynamic = dynamic[len(dynamic)-1]
+			dynamic = dynamic[:len(dynamic)-1]
+		case opReturn:
+			return i.pop(), nil
+		default:
+			panic(fmt.Sprintf("unknown opcode %d", op))
+		}
+	}
+}
+
+// callValue calls the function below argc arguments on top of the stack.
+// Compiled functions get their arguments copied directly into their frame.
+func (i *Interpreter) callValue(line int, argc int) (interface{}, error) {
+	start := len(i.stack) - argc
+	args := i.stack[start:]
+
+	if fun, ok := i.stack[start-1].(*compiledFunction); ok {
+		if fun.proto.rest < 0 && argc != len(fun.proto.params) {
+			return nil, &executionError{line, fmt.Sprintf("Expected %d arguments but got %d", len(fun.proto.params), argc)}
+		}
+
+		fr, err := fun.newFrame(line, args)
+		if err != nil {
+			return nil, err
+		}
+
+		i.truncate(start - 1)
+		return i.run(fun.proto, fr, fun.globals)
+	}
+
+	fun := i.stack[start-1].(Function)
+
+	var arguments []interface{}
+	if argc > 0 {
+		arguments = append(arguments, args...)
+	}
+
+	i.truncate(start - 1)
+	return callFunction(line, i, fun, arguments)
+}
+
+// selectClause runs the select of a table with the channels and values on
+// the stack. It pushes the received value and returns the address of the
+// chosen clause.
+func (i *Interpreter) selectClause(line int, table *selectTable) (int, error) {
+	var operands int
+
+	for _, clause := range table.expr.Clauses {
+		switch clause.Kind.Lexeme {
+		case "recv":
+			operands++
+		case "send":
+			operands += 2
+		}
+	}
+
+	values := i.stack[len(i.stack)-operands:]
+
+	var cases []reflect.SelectCase
+	var channels []*Channel
+
+	for _, clause := range table.expr.Clauses {
+		if clause.Kind.Lexeme == "default" {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+			channels = append(channels, nil)
+			continue
+		}
+
+		ch := values[0].(*Channel)
+		channels = append(channels, ch)
+
+		if clause.Kind.Lexeme == "recv" {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch.ch)})
+			values = values[1:]
+			continue
+		}
+
+		sent := values[1]
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(ch.ch), Send: reflect.ValueOf(&sent).Elem()})
+		values = values[2:]
+	}
+
+	i.truncate(len(i.stack) - operands)
+
+	chosen, received, ok := selectCase(cases)
+	if chosen < 0 {
+		return 0, &executionError{line, "Cannot send on a closed channel"}
+	}
+
+	var val interface{}
+
+	if table.expr.Clauses[chosen].Kind.Lexeme == "recv" {
+		if ok {
+			val = received.Interface()
+		} else if err := channels[chosen].err; err != nil {
+			return 0, err
+		}
+	}
+
+	i.push(val)
+
+	return table.targets[chosen], nil
+}
@@ -0,0 +1,154 @@
+package minimalisp_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+
+	. "bakku.dev/minimalisp"
+)
+
+func TestVM_ClosuresKeepTheirFrames(t *testing.T) {
+	src := `
+	(defun counter-from (n)
+	  (let (start n)
+	    (lambda (step) (+ start step))))
+	(defvar from-one (counter-from 1))
+	(defvar from-ten (counter-from 10))
+	(map (lambda (f) (apply f '(5))) '(from-one from-ten))`
+
+	expectResult(t, src, "(6 15)")
+}
+
+func TestVM_ReportsErrorsWithLines(t *testing.T) {
+	err := expectError(t, "(defun f (x)\n  (undefined x))\n(f 1)")
+	if !strings.Contains(err.Error(), "[line 2]") {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	err = expectError(t, "(defun f (a b) a)\n(f 1)")
+	if !strings.Contains(err.Error(), "Expected 2 arguments but got 1") {
+		t.Fatalf("Unexpected error %v", err)
+	}
+}
+
+func TestVM_DocumentsFunctions(t *testing.T) {
+	interpreter := NewInterpreter(WithBackend(Bytecode))
+
+	if _, err := interpreter.Interpret(parse(t, `(defun add ((a b) c) "Adds." (+ a b c))`)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	doc, err := interpreter.Doc("add")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if doc.Signature != "(add (a b) c)" || doc.Description != "Adds." {
+		t.Fatalf("Unexpected documentation %v", doc)
+	}
+}
+
+func TestVM_RestoresStateAfterErrors(t *testing.T) {
+	interpreter := NewInterpreter(WithBackend(Bytecode))
+
+	if _, err := interpreter.Interpret(parse(t, "(defparameter *x* 1) (defun get-x () *x*)")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := interpreter.Interpret(parse(t, "(parameterize ((*x* 2)) (+ 1 (/ 1 0)))")); err == nil {
+		t.Fatalf("Expected an error")
+	}
+
+	ret, err := interpreter.Interpret(parse(t, "(get-x)"))
+	if err != nil || ret != 1.0 {
+		t.Fatalf("Expected 1, got %v and %v", ret, err)
+	}
+}
+
+func TestVM_RunsConcurrently(t *testing.T) {
+	interpreter := NewInterpreter(WithBackend(Bytecode))
+	expressions := parse(t, "(defun fib (n) (if (< n 2) n (+ (fib (- n 1)) (fib (- n 2)))))")
+
+	if _, err := interpreter.Interpret(expressions); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	call := parse(t, "(fib 15)")
+
+	var wg sync.WaitGroup
+
+	for n := 0; n < 4; n++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			ret, err := interpreter.Interpret(call)
+			if err != nil || ret != 610.0 {
+				t.Errorf("Expected 610, got %v and %v", ret, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	expectResult(t, "(defun sq (x) (* x x)) (pmap sq (range 1 5))", "(1 4 9 16)")
+}
+
+// benchmarkSource runs a program with a backend. The definitions are
+// interpreted once and the call is measured.
+func benchmarkSource(b *testing.B, backend Backend, definitions string, call string) {
+	interpreter := NewInterpreter(WithBackend(backend))
+
+	if _, err := interpreter.Interpret(parseBenchmark(b, definitions)); err != nil {
+		b.Fatalf("Expected no error, got %v", err)
+	}
+
+	expressions := parseBenchmark(b, call)
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if _, err := interpreter.Interpret(expressions); err != nil {
+			b.Fatalf("Expected no error, got %v", err)
+		}
+	}
+}
+
+func parseBenchmark(b *testing.B, src string) []Expression {
+	tokens, ok := NewScanner(src, ioutil.Discard).Scan()
+	if !ok {
+		b.Fatalf("Expected source to scan")
+	}
+
+	expressions, err := NewParser(tokens).Parse()
+	if err != nil {
+		b.Fatalf("Expected source to parse, got %v", err)
+	}
+
+	return expressions
+}
+
+const fibSource = "(defun fib (n) (if (< n 2) n (+ (fib (- n 1)) (fib (- n 2)))))"
+
+const sumSource = `
+(defun sum-squares (numbers)
+  (fold (lambda (acc x) (let* (sq (* x x) total (+ acc sq)) total)) 0 numbers))`
+
+func BenchmarkTreeWalker_Fib(b *testing.B) {
+	benchmarkSource(b, TreeWalker, fibSource, "(fib 20)")
+}
+
+func BenchmarkBytecode_Fib(b *testing.B) {
+	benchmarkSource(b, Bytecode, fibSource, "(fib 20)")
+}
+
+func BenchmarkTreeWalker_SumSquares(b *testing.B) {
+	benchmarkSource(b, TreeWalker, sumSource, "(sum-squares (range 1 10000))")
+}
+
+func BenchmarkBytecode_SumSquares(b *testing.B) {
+	benchmarkSource(b, Bytecode, sumSource, "(sum-squares (range 1 10000))")
+}